@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rwejlgaard/org/internal/capture"
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/hooks"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// asksForInput reports whether a template has prompts besides the captured text
+func asksForInput(tmpl *config.CaptureTemplate) bool {
+	for _, name := range capture.Prompts(tmpl) {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// captureWithTemplate files text with a template without starting the UI. Lines after the first
+// become notes, so piped text keeps its line breaks.
+func captureWithTemplate(orgFile *model.OrgFile, cfg *config.Config, tmpl *config.CaptureTemplate, text string) error {
+	now := cfg.Now()
+	item := capture.NewItem(tmpl, cfg, map[string]string{"": text}, now)
+	if item.Title == "" {
+		return fmt.Errorf("nothing to capture")
+	}
+
+	// Without a target file, multi-file captures go to the first file
+	var defaultFile *model.Item
+	if len(orgFile.Items) > 0 && orgFile.Items[0].SourceFile != "" {
+		defaultFile = orgFile.Items[0]
+	}
+
+	result, err := capture.Insert(orgFile, cfg, tmpl, item, defaultFile, now)
+	if err != nil {
+		return err
+	}
+	saved := result.Saved
+	if saved == nil {
+		if err := parser.Save(orgFile); err != nil {
+			return err
+		}
+		saved = orgFile
+	}
+
+	fmt.Fprintf(os.Stderr, "Captured to %s\n", filepath.Base(result.File))
+	if err := hooks.Run(cfg, hooks.Captured, item, result.File, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	afterSave(saved, cfg)
+	return nil
+}
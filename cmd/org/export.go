@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/convert"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// runExport handles `org export <format> [flags] [file]`
+func runExport(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: org export <format> [flags] [file]")
+		fmt.Fprintln(os.Stderr, "Formats: ics, md, html, json, todotxt, taskwarrior")
+		os.Exit(2)
+	}
+
+	format := args[0]
+	flags := flag.NewFlagSet("export "+format, flag.ExitOnError)
+	var outputPath string
+	var multiMode bool
+	flags.StringVar(&outputPath, "o", "", "Write to this file instead of stdout")
+	flags.BoolVar(&multiMode, "m", false, "Export all org files in the directory")
+
+	var export func(w io.Writer, orgFile *model.OrgFile, cfg *config.Config) error
+	switch format {
+	case "ics":
+		var opts convert.ICSOptions
+		flags.BoolVar(&opts.IncludeClock, "clock", false, "Include clock entries as events")
+		export = func(w io.Writer, orgFile *model.OrgFile, cfg *config.Config) error {
+			return convert.ExportICS(w, orgFile, cfg, opts)
+		}
+	case "md":
+		var opts convert.MarkdownOptions
+		flags.BoolVar(&opts.SkipDone, "no-done", false, "Leave out DONE items")
+		flags.BoolVar(&opts.SkipDrawers, "no-drawers", false, "Leave out LOGBOOK and PROPERTIES drawers")
+		flags.BoolVar(&opts.SkipNotes, "no-notes", false, "Leave out notes")
+		export = func(w io.Writer, orgFile *model.OrgFile, cfg *config.Config) error {
+			return convert.ExportMarkdown(w, orgFile.Items, cfg, opts)
+		}
+	case "html":
+		var opts convert.HTMLOptions
+		flags.StringVar(&opts.Title, "title", "", "Page title (default: the file name)")
+		flags.BoolVar(&opts.SkipDone, "no-done", false, "Leave out DONE items")
+		export = func(w io.Writer, orgFile *model.OrgFile, cfg *config.Config) error {
+			if opts.Title == "" {
+				opts.Title = strings.TrimSuffix(filepath.Base(orgFile.Path), ".org")
+			}
+			return convert.ExportHTML(w, orgFile.Items, cfg, opts)
+		}
+	case "json":
+		export = func(w io.Writer, orgFile *model.OrgFile, cfg *config.Config) error {
+			return convert.ExportJSON(w, orgFile)
+		}
+	case "todotxt":
+		export = convert.ExportTodoTxt
+	case "taskwarrior":
+		export = convert.ExportTaskwarrior
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown export format: %s\n", format)
+		os.Exit(2)
+	}
+	flags.Parse(args[1:])
+
+	cfg := loadConfig()
+	orgFile, err := loadOrgFile(flags.Arg(0), multiMode, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	err = writeOutput(outputPath, func(w io.Writer) error {
+		return export(w, orgFile, cfg)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error exporting: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// writeOutput runs write against the output file, or stdout if no path is given
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/convert"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// runImport handles `org import <format> [flags] <source> [target]`
+func runImport(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: org import <format> [flags] <source> [target.org]")
+		fmt.Fprintln(os.Stderr, "Formats: ics, md, json, todotxt, taskwarrior")
+		os.Exit(2)
+	}
+
+	format := args[0]
+	flags := flag.NewFlagSet("import "+format, flag.ExitOnError)
+
+	var importFunc func(r io.Reader, orgFile *model.OrgFile, cfg *config.Config) (string, error)
+	switch format {
+	case "ics":
+		importFunc = func(r io.Reader, orgFile *model.OrgFile, cfg *config.Config) (string, error) {
+			added, skipped, err := convert.ImportICS(r, orgFile, cfg)
+			return fmt.Sprintf("Imported %d items (%d already imported)", added, skipped), err
+		}
+	case "md":
+		importFunc = func(r io.Reader, orgFile *model.OrgFile, cfg *config.Config) (string, error) {
+			name := "Imported notes"
+			if flags.Arg(0) != "-" {
+				name = filepath.Base(flags.Arg(0))
+			}
+			added, err := convert.ImportMarkdown(r, orgFile, cfg, name)
+			return fmt.Sprintf("Imported %d headings", added), err
+		}
+	case "json":
+		var replace bool
+		flags.BoolVar(&replace, "replace", false, "Replace the target's items instead of adding to them")
+		importFunc = func(r io.Reader, orgFile *model.OrgFile, cfg *config.Config) (string, error) {
+			added, err := convert.ImportJSON(r, orgFile, cfg, replace)
+			return fmt.Sprintf("Imported %d items", added), err
+		}
+	case "todotxt":
+		var sync bool
+		flags.BoolVar(&sync, "sync", false, "Update matching items instead of adding them again")
+		importFunc = func(r io.Reader, orgFile *model.OrgFile, cfg *config.Config) (string, error) {
+			added, updated, err := convert.ImportTodoTxt(r, orgFile, cfg, sync)
+			return fmt.Sprintf("Imported %d tasks (%d updated)", added, updated), err
+		}
+	case "taskwarrior":
+		importFunc = func(r io.Reader, orgFile *model.OrgFile, cfg *config.Config) (string, error) {
+			added, skipped, err := convert.ImportTaskwarrior(r, orgFile, cfg)
+			return fmt.Sprintf("Imported %d tasks (%d skipped)", added, skipped), err
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown import format: %s\n", format)
+		os.Exit(2)
+	}
+	flags.Parse(args[1:])
+
+	if flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: org import %s [flags] <source> [target.org]\n", format)
+		os.Exit(2)
+	}
+
+	if err := importInto(flags.Arg(0), flags.Arg(1), importFunc); err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// importInto reads the source file ("-" for stdin), imports it into the target org file
+// (default ./todo.org) and saves the result
+func importInto(sourcePath, targetPath string, importFunc func(r io.Reader, orgFile *model.OrgFile, cfg *config.Config) (string, error)) error {
+	var source io.Reader = os.Stdin
+	if sourcePath != "-" {
+		file, err := os.Open(sourcePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		source = file
+	}
+
+	cfg := loadConfig()
+	orgFile, err := loadOrgFile(targetPath, false, cfg)
+	if err != nil {
+		return err
+	}
+
+	summary, err := importFunc(source, orgFile, cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := parser.Save(orgFile); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s into %s\n", summary, orgFile.Path)
+	afterSave(orgFile, cfg)
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -9,12 +10,35 @@ import (
 	"path/filepath"
 
 	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/gitsync"
+	"github.com/rwejlgaard/org/internal/hooks"
 	"github.com/rwejlgaard/org/internal/model"
 	"github.com/rwejlgaard/org/internal/parser"
 	"github.com/rwejlgaard/org/internal/ui"
 )
 
 func main() {
+	// Subcommands are dispatched before flag parsing
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "rpc":
+			runRPC(os.Args[2:])
+			return
+		case "notify":
+			runNotify(os.Args[2:])
+			return
+		}
+	}
+
 	var filePath string
 	var multiMode bool
 	var captureMode bool
@@ -22,7 +46,13 @@ func main() {
 	flag.BoolVar(&multiMode, "m", false, "Load all org files in current directory (shorthand)")
 	flag.BoolVar(&captureMode, "capture", false, "Start in capture mode")
 	flag.BoolVar(&captureMode, "c", false, "Start in capture mode (shorthand)")
+	var template string
+	flag.StringVar(&template, "template", "", "Capture with the template with this key or name")
+	flag.StringVar(&template, "t", "", "Capture with the template with this key or name (shorthand)")
 	flag.Parse()
+	if template != "" {
+		captureMode = true
+	}
 
 	// Check for positional argument or capture text
 	var captureText string
@@ -41,6 +71,7 @@ func main() {
 	}
 
 	// Check if input is being piped
+	var piped bool
 	stat, _ := os.Stdin.Stat()
 	if (stat.Mode() & os.ModeCharDevice) == 0 {
 		// Data is being piped to stdin
@@ -48,6 +79,7 @@ func main() {
 		pipedText, err := io.ReadAll(reader)
 		if err == nil && len(pipedText) > 0 {
 			captureMode = true
+			piped = true
 			captureText = string(pipedText)
 			// If no file path was provided via args, check if last arg could be a path
 			if filePath == "" && len(flag.Args()) > 0 {
@@ -56,15 +88,90 @@ func main() {
 		}
 	}
 
-	// Load configuration
+	cfg := loadConfig()
+	var tmpl *config.CaptureTemplate
+	if template != "" {
+		if tmpl = cfg.GetCaptureTemplate(template); tmpl == nil {
+			fmt.Fprintf(os.Stderr, "Unknown capture template %q\n", template)
+			os.Exit(1)
+		}
+	}
+	pullOrgFiles(filePath, cfg)
+	orgFile, err := loadOrgFile(filePath, multiMode, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	// Piped text is captured straight away when the template needs nothing else
+	if piped && tmpl != nil && !asksForInput(tmpl) {
+		if err := captureWithTemplate(orgFile, cfg, tmpl, captureText); err != nil {
+			fmt.Fprintf(os.Stderr, "Error capturing: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Run the UI
+	if err := ui.RunUI(orgFile, cfg, captureMode, captureText, template); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running UI: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Save on exit
+	if err := parser.Save(orgFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving file: %v\n", err)
+		os.Exit(1)
+	}
+	afterSave(orgFile, cfg)
+}
+
+// afterSave commits the saved files to git and runs the hook for saved files, reporting
+// failures without failing the command
+func afterSave(orgFile *model.OrgFile, cfg *config.Config) {
+	if err := gitsync.CommitSaved(orgFile, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Changes saved but not committed: %v\n", err)
+	}
+	if err := hooks.Run(cfg, hooks.Saved, nil, orgFile.Path, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// pullOrgFiles pulls the git repository holding the org files at filePath, if pulling is
+// enabled. A conflict stops the command so that it can be resolved first; other
+// failures, such as being offline, only warn.
+func pullOrgFiles(filePath string, cfg *config.Config) {
+	if filePath == "" {
+		filePath = "."
+	}
+	err := gitsync.Pull(filePath, cfg)
+	var conflict *gitsync.ConflictError
+	if errors.As(err, &conflict) {
+		fmt.Fprintf(os.Stderr, "Error: %v\nYour files were left as they were. Resolve the conflict with git and try again.\n", err)
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// loadConfig loads the configuration, falling back to the defaults on error
+func loadConfig() *config.Config {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: Error loading config, using defaults: %v\n", err)
 		cfg = config.DefaultConfig()
 	}
 
-	var orgFile *model.OrgFile
+	// Org dates are wall clock times, read and compared in the configured timezone
+	if _, err := cfg.Location(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, using the system timezone\n", err)
+	}
+	return cfg
+}
 
+// loadOrgFile parses the org file at filePath (default ./todo.org), or in multi-file mode
+// all org files in the directory it points to (default the current directory)
+func loadOrgFile(filePath string, multiMode bool, cfg *config.Config) (*model.OrgFile, error) {
 	if multiMode {
 		// Multi-file mode: load all .org files in directory
 		var dirPath string
@@ -81,46 +188,32 @@ func main() {
 			// Use current directory
 			cwd, err := os.Getwd()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
-				os.Exit(1)
+				return nil, fmt.Errorf("Error getting current directory: %v", err)
 			}
 			dirPath = cwd
 		}
 
-		orgFile, err = parser.ParseMultipleOrgFiles(dirPath, cfg)
+		orgFile, err := parser.ParseMultipleOrgFiles(dirPath, cfg)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing org files: %v\n", err)
-			os.Exit(1)
-		}
-	} else {
-		// Single file mode (default)
-		if filePath == "" {
-			// Default to ./todo.org
-			cwd, err := os.Getwd()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
-				os.Exit(1)
-			}
-			filePath = filepath.Join(cwd, "todo.org")
+			return nil, fmt.Errorf("Error parsing org files: %v", err)
 		}
+		return orgFile, nil
+	}
 
-		// Parse the org file
-		orgFile, err = parser.ParseOrgFile(filePath, cfg)
+	// Single file mode (default)
+	if filePath == "" {
+		// Default to ./todo.org
+		cwd, err := os.Getwd()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing org file: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("Error getting current directory: %v", err)
 		}
+		filePath = filepath.Join(cwd, "todo.org")
 	}
 
-	// Run the UI
-	if err := ui.RunUI(orgFile, cfg, captureMode, captureText); err != nil {
-		fmt.Fprintf(os.Stderr, "Error running UI: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Save on exit
-	if err := parser.Save(orgFile); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving file: %v\n", err)
-		os.Exit(1)
+	// Parse the org file
+	orgFile, err := parser.ParseOrgFile(filePath, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing org file: %v", err)
 	}
+	return orgFile, nil
 }
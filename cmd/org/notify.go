@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/notify"
+)
+
+// runNotify handles `org notify [flags] [file]`, which sends reminders of timed items and
+// deadlines until it is stopped
+func runNotify(args []string) {
+	flags := flag.NewFlagSet("notify", flag.ExitOnError)
+	var multiMode bool
+	var once bool
+	var interval time.Duration
+	flags.BoolVar(&multiMode, "m", false, "Watch all org files in the directory")
+	flags.BoolVar(&once, "once", false, "Send the reminders that are due and exit")
+	flags.DurationVar(&interval, "interval", time.Minute, "How often to check for reminders")
+	flags.Parse(args)
+
+	cfg := loadConfig()
+	load, err := orgFileLoader(flags.Arg(0), multiMode, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	for {
+		// Read the files again every time to pick up changes
+		if orgFile, err := load(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading org files: %v\n", err)
+		} else {
+			sent, err := notify.Check(orgFile, cfg, cfg.Now(), os.Stdout)
+			for _, reminder := range sent {
+				fmt.Printf("%s %s: %s\n", cfg.Now().Format("15:04"), reminder.Title, reminder.Message)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error sending reminders: %v\n", err)
+			}
+		}
+
+		if once {
+			return
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/server"
+)
+
+// runRPC handles `org rpc [flags] [file]`
+func runRPC(args []string) {
+	flags := flag.NewFlagSet("rpc", flag.ExitOnError)
+	var multiMode bool
+	flags.BoolVar(&multiMode, "m", false, "Load all org files in the directory")
+	flags.Parse(args)
+
+	cfg := loadConfig()
+	pullOrgFiles(flags.Arg(0), cfg)
+	load, err := orgFileLoader(flags.Arg(0), multiMode, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if err := server.NewRPC(load, cfg).Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading requests: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// orgFileLoader checks that the org file can be read and returns a function that reads it
+// again, from the same path even if the working directory changes
+func orgFileLoader(filePath string, multiMode bool, cfg *config.Config) (func() (*model.OrgFile, error), error) {
+	orgFile, err := loadOrgFile(filePath, multiMode, cfg)
+	if err != nil {
+		return nil, err
+	}
+	filePath = orgFile.Path
+	return func() (*model.OrgFile, error) {
+		return loadOrgFile(filePath, multiMode, cfg)
+	}, nil
+}
+
+// runServe handles `org serve [flags] [file]`
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	var addr string
+	var multiMode bool
+	flags.StringVar(&addr, "addr", "127.0.0.1:7777", "Address to listen on")
+	flags.BoolVar(&multiMode, "m", false, "Serve all org files in the directory")
+	flags.Parse(args)
+
+	cfg := loadConfig()
+	pullOrgFiles(flags.Arg(0), cfg)
+	load, err := orgFileLoader(flags.Arg(0), multiMode, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving on http://%s\n", addr)
+	if err := server.New(load, cfg).ListenAndServe(addr); err != nil {
+		fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,319 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// placeholderPattern matches the placeholders of a template: %text, %date, %time,
+// %clipboard, %prompt:Name and %% for a literal percent sign
+var placeholderPattern = regexp.MustCompile(`%(text|date|time|clipboard|prompt:[\w-]+|%)`)
+
+// datePrefixPattern matches the dates that start the headings of a date tree: a year, a
+// month or a day
+var datePrefixPattern = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+
+// plainTemplate is used for captures without a template
+var plainTemplate = config.CaptureTemplate{}
+
+// Prompts returns the names of the values a template asks for, in the order they first
+// appear. A template that includes the captured text asks for it first, under the name "".
+func Prompts(tmpl *config.CaptureTemplate) []string {
+	var prompts []string
+	hasText := false
+	seen := make(map[string]bool)
+	for _, match := range placeholderPattern.FindAllStringSubmatch(body(tmpl), -1) {
+		if match[1] == "text" {
+			hasText = true
+		} else if name, ok := strings.CutPrefix(match[1], "prompt:"); ok && !seen[name] {
+			seen[name] = true
+			prompts = append(prompts, name)
+		}
+	}
+	if hasText {
+		prompts = append([]string{""}, prompts...)
+	}
+	return prompts
+}
+
+// NewItem creates the entry for a capture. values holds the answers to the template's
+// prompts, with the captured text under "". tmpl may be nil for a plain capture.
+func NewItem(tmpl *config.CaptureTemplate, cfg *config.Config, values map[string]string, now time.Time) *model.Item {
+	if tmpl == nil {
+		tmpl = &plainTemplate
+	}
+
+	var clip *string
+	text := placeholderPattern.ReplaceAllStringFunc(body(tmpl), func(placeholder string) string {
+		switch name := placeholder[1:]; {
+		case name == "text":
+			return values[""]
+		case name == "date":
+			return now.Format("2006-01-02")
+		case name == "time":
+			return now.Format("15:04")
+		case name == "clipboard":
+			if clip == nil {
+				content, _ := clipboard.ReadAll()
+				content = strings.TrimRight(content, "\n")
+				clip = &content
+			}
+			return *clip
+		case name == "%":
+			return "%"
+		default:
+			return values[strings.TrimPrefix(name, "prompt:")]
+		}
+	})
+
+	title, notes, _ := strings.Cut(text, "\n")
+	item := &model.Item{
+		State:    model.TodoState(cfg.GetDefaultNewTaskState()),
+		Title:    strings.TrimSpace(title),
+		Tags:     append([]string{}, tmpl.Tags...),
+		Notes:    []string{},
+		Children: []*model.Item{},
+	}
+	if strings.EqualFold(tmpl.State, "none") {
+		item.State = model.StateNone
+	} else if tmpl.State != "" {
+		item.State = model.TodoState(tmpl.State)
+	}
+	if tmpl.Priority != "" {
+		item.Priority = model.Priority(strings.ToUpper(tmpl.Priority))
+	}
+	if tmpl.Effort != "" {
+		item.SetProperty("EFFORT", tmpl.Effort)
+	}
+	if strings.TrimSpace(notes) != "" {
+		for _, line := range strings.Split(strings.TrimRight(notes, "\n"), "\n") {
+			// Lines starting with a star would become headings
+			if strings.HasPrefix(line, "*") {
+				line = " " + line
+			}
+			item.Notes = append(item.Notes, line)
+		}
+	}
+	parser.ScanTimestamps(item, cfg.Zone())
+	return item
+}
+
+// Result tells where a capture went
+type Result struct {
+	Parent *model.Item    // Heading the entry was filed under, nil at the top of a file
+	File   string         // File the entry was added to
+	Saved  *model.OrgFile // The target file, if it isn't part of the org file and was saved on its own
+}
+
+// Insert files a captured entry at the template's target. Entries without a target file
+// go into defaultFile, a file item in multi-file mode, or at the top level of orgFile if
+// it is nil. Entries go first in a file, or last under a heading. now picks the headings
+// of a date tree. A target file that isn't loaded is read, changed and saved right away.
+func Insert(orgFile *model.OrgFile, cfg *config.Config, tmpl *config.CaptureTemplate, item *model.Item, defaultFile *model.Item, now time.Time) (*Result, error) {
+	if tmpl == nil {
+		tmpl = &plainTemplate
+	}
+	multi := isMultiFile(orgFile)
+
+	// Find the file item to capture into in multi-file mode
+	var root *model.Item
+	file := orgFile.Path
+	switch {
+	case tmpl.File != "" && multi:
+		path := resolvePath(orgFile, tmpl.File)
+		for _, fileItem := range orgFile.Items {
+			if samePath(fileItem.SourceFile, path) {
+				root = fileItem
+			}
+		}
+		if root == nil {
+			return insertIntoFile(path, cfg, tmpl, item, now)
+		}
+		file = root.SourceFile
+	case tmpl.File != "":
+		if path := resolvePath(orgFile, tmpl.File); !samePath(orgFile.Path, path) {
+			return insertIntoFile(path, cfg, tmpl, item, now)
+		}
+	case multi:
+		if defaultFile == nil {
+			return nil, fmt.Errorf("no file to capture to")
+		}
+		root, file = defaultFile, defaultFile.SourceFile
+	}
+
+	parent := place(&orgFile.Items, root, tmpl, item, now)
+	if root != nil {
+		// New headings and the entry belong to the file too
+		setSourceFile(root, file)
+	}
+	return &Result{Parent: parent, File: file}, nil
+}
+
+// insertIntoFile captures into a file that isn't loaded and saves it
+func insertIntoFile(path string, cfg *config.Config, tmpl *config.CaptureTemplate, item *model.Item, now time.Time) (*Result, error) {
+	target, err := parser.ParseOrgFile(path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	parent := place(&target.Items, nil, tmpl, item, now)
+	if err := parser.Save(target); err != nil {
+		return nil, err
+	}
+	return &Result{Parent: parent, File: path, Saved: target}, nil
+}
+
+// place adds item under the template's heading path and date tree below root, or to
+// items at the top level if root is nil, creating the headings that are missing. It
+// returns the parent of the item.
+func place(items *[]*model.Item, root *model.Item, tmpl *config.CaptureTemplate, item *model.Item, now time.Time) *model.Item {
+	parent := root
+	for _, title := range tmpl.Heading {
+		parent = findOrCreateHeading(items, parent, title)
+	}
+	if tmpl.DateTree {
+		parent = findOrCreateDateHeading(items, parent, now.Format("2006"), now.Format("2006"))
+		parent = findOrCreateDateHeading(items, parent, now.Format("2006-01"), now.Format("2006-01 January"))
+		parent = findOrCreateDateHeading(items, parent, now.Format("2006-01-02"), now.Format("2006-01-02 Monday"))
+	}
+
+	if parent == nil {
+		setLevel(item, 1)
+		*items = append([]*model.Item{item}, *items...)
+		return nil
+	}
+	setLevel(item, parent.Level+1)
+	if root != nil && parent == root {
+		// Entries go first in a file, as with a plain capture
+		parent.Children = append([]*model.Item{item}, parent.Children...)
+	} else {
+		parent.Children = append(parent.Children, item)
+	}
+	parent.Folded = false
+	return parent
+}
+
+// findOrCreateHeading returns the child of parent (or top-level item if parent is nil)
+// with the given title, adding a heading at the end if there is none
+func findOrCreateHeading(items *[]*model.Item, parent *model.Item, title string) *model.Item {
+	children := items
+	level := 1
+	if parent != nil {
+		children = &parent.Children
+		level = parent.Level + 1
+		parent.Folded = false
+	}
+	for _, child := range *children {
+		if strings.EqualFold(strings.TrimSpace(child.Title), strings.TrimSpace(title)) {
+			return child
+		}
+	}
+
+	heading := &model.Item{
+		Level:    level,
+		Title:    title,
+		Tags:     []string{},
+		Notes:    []string{},
+		Children: []*model.Item{},
+	}
+	*children = append(*children, heading)
+	return heading
+}
+
+// findOrCreateDateHeading returns the child of parent (or top-level item if parent is
+// nil) whose title starts with date, such as "2025-01" for "2025-01 January". A missing
+// heading is added with the given title before the first sibling with a later date, so
+// that the tree stays sorted.
+func findOrCreateDateHeading(items *[]*model.Item, parent *model.Item, date, title string) *model.Item {
+	children := items
+	level := 1
+	if parent != nil {
+		children = &parent.Children
+		level = parent.Level + 1
+		parent.Folded = false
+	}
+
+	position := len(*children)
+	for i, child := range *children {
+		childDate, _, _ := strings.Cut(strings.TrimSpace(child.Title), " ")
+		if childDate == date {
+			return child
+		}
+		if len(childDate) == len(date) && datePrefixPattern.MatchString(childDate) && childDate > date && position == len(*children) {
+			position = i
+		}
+	}
+
+	heading := &model.Item{
+		Level:    level,
+		Title:    title,
+		Tags:     []string{},
+		Notes:    []string{},
+		Children: []*model.Item{},
+	}
+	*children = append((*children)[:position], append([]*model.Item{heading}, (*children)[position:]...)...)
+	return heading
+}
+
+// setLevel sets the level of an item, moving its children along with it
+func setLevel(item *model.Item, level int) {
+	shift := level - item.Level
+	item.Level = level
+	for _, child := range item.Children {
+		setLevel(child, child.Level+shift)
+	}
+}
+
+// setSourceFile records the file an item (and its children) belongs to in multi-file mode
+func setSourceFile(item *model.Item, file string) {
+	item.SourceFile = file
+	for _, child := range item.Children {
+		setSourceFile(child, file)
+	}
+}
+
+// resolvePath expands ~ and makes a target path relative to the org file's directory
+// (or the directory itself in multi-file mode) absolute
+func resolvePath(orgFile *model.OrgFile, path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	dir := filepath.Dir(orgFile.Path)
+	if isMultiFile(orgFile) {
+		dir = orgFile.Path
+	}
+	return filepath.Join(dir, path)
+}
+
+// samePath reports whether two paths name the same file
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// isMultiFile reports whether the org file holds a directory of files
+func isMultiFile(orgFile *model.OrgFile) bool {
+	return len(orgFile.Items) > 0 && orgFile.Items[0].SourceFile != ""
+}
+
+// body returns the text of a template, which is just the captured text by default
+func body(tmpl *config.CaptureTemplate) string {
+	if tmpl == nil || tmpl.Template == "" {
+		return "%text"
+	}
+	return tmpl.Template
+}
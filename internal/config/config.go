@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/charmbracelet/bubbles/key"
@@ -11,11 +13,17 @@ import (
 
 // Config represents the application configuration
 type Config struct {
+	Timezone    string            `toml:"timezone"` // IANA name of the timezone dates are in, the system's if empty
 	Keybindings KeybindingsConfig `toml:"keybindings"`
 	Colors      ColorsConfig      `toml:"colors"`
 	Tags        TagsConfig        `toml:"tags"`
 	States      StatesConfig      `toml:"states"`
 	UI          UIConfig          `toml:"ui"`
+	Hooks       HooksConfig       `toml:"hooks"`
+	Git         GitConfig         `toml:"git"`
+	Capture     CaptureConfig     `toml:"capture"`
+	Notify      NotifyConfig      `toml:"notify"`
+	Pomodoro    PomodoroConfig    `toml:"pomodoro"`
 }
 
 // KeybindingsConfig holds all keybinding configurations
@@ -48,6 +56,9 @@ type KeybindingsConfig struct {
 	Quit          []string `toml:"quit"`
 	Settings      []string `toml:"settings"`
 	TagItem       []string `toml:"tag_item"`
+	ShowHistory   []string `toml:"show_history"`
+	Export        []string `toml:"export"`
+	Pomodoro      []string `toml:"pomodoro"`
 }
 
 // ColorsConfig holds color configurations
@@ -60,6 +71,8 @@ type ColorsConfig struct {
 	Title     string `toml:"title"`
 	Scheduled string `toml:"scheduled"`
 	Overdue   string `toml:"overdue"`
+	DueToday  string `toml:"due_today"`
+	Warning   string `toml:"warning"` // Deadlines within their warning period
 	Status    string `toml:"status"`
 	Note      string `toml:"note"`
 	Folded    string `toml:"folded"`
@@ -80,8 +93,10 @@ type TagsConfig struct {
 
 // StateConfig represents a single TODO state configuration
 type StateConfig struct {
-	Name  string `toml:"name"`
-	Color string `toml:"color"`
+	Name    string `toml:"name"`
+	Color   string `toml:"color"`
+	Log     bool   `toml:"log"`      // Record a timestamped state change in the LOGBOOK when entering this state
+	LogNote bool   `toml:"log_note"` // Prompt for a note when entering this state (implies Log)
 }
 
 // StatesConfig holds TODO state configurations
@@ -98,10 +113,72 @@ type UIConfig struct {
 	OrgSyntaxHighlighting bool   `toml:"org_syntax_highlighting"`
 	ShowIndentationGuides bool   `toml:"show_indentation_guides"`
 	IndentationGuideColor string `toml:"indentation_guide_color"`
+	HabitDaysBefore       int    `toml:"habit_days_before"`     // Past days shown in habit consistency graphs
+	HabitDaysAfter        int    `toml:"habit_days_after"`      // Future days shown in habit consistency graphs
+	DeadlineWarningDays   *int   `toml:"deadline_warning_days"` // Days before a deadline it starts showing up, unless it has a -Nd cookie; nil if unset, as 0 turns warnings off
+}
+
+// HooksConfig maps events to shell commands that are run when they happen. The item is
+// passed as JSON on stdin, with its main fields in ORG_* environment variables.
+type HooksConfig struct {
+	StateChanged string `toml:"state_changed"`
+	Captured     string `toml:"captured"`
+	ClockIn      string `toml:"clock_in"`
+	ClockOut     string `toml:"clock_out"`
+	DeadlineSet  string `toml:"deadline_set"`
+	Deleted      string `toml:"deleted"`
+	Saved        string `toml:"saved"`
+	Reminder     string `toml:"reminder"` // Shows a reminder, instead of the terminal's notification
+	Pomodoro     string `toml:"pomodoro"` // A pomodoro timer starts, stops or switches between work and break
+	Timeout      int    `toml:"timeout"`  // Seconds a hook may run before it is killed
+}
+
+// GitConfig controls committing org files that live in a git repository
+type GitConfig struct {
+	AutoCommit bool `toml:"auto_commit"` // Commit the org files every time they are saved
+	Pull       bool `toml:"pull"`        // Pull and rebase when starting
 }
 
+// NotifyConfig controls reminders of timed scheduled items, appointments and deadlines
+type NotifyConfig struct {
+	Enabled       bool   `toml:"enabled"`        // Send reminders while the TUI is running
+	MinutesBefore int    `toml:"minutes_before"` // Minutes before a timed item starts to remind of it
+	DeadlineTime  string `toml:"deadline_time"`  // Time of day to remind of the deadlines due that day
+}
+
+// PomodoroConfig sets the length of the work and break periods of the pomodoro timer
+type PomodoroConfig struct {
+	WorkMinutes  int `toml:"work_minutes"`
+	BreakMinutes int `toml:"break_minutes"`
+}
+
+// CaptureConfig holds the templates offered when capturing
+type CaptureConfig struct {
+	Templates []CaptureTemplate `toml:"templates"`
+}
+
+// CaptureTemplate describes what a capture creates and where it goes. The template's first
+// line is the heading's title and the rest its notes; placeholders in it are filled in
+// when capturing.
+type CaptureTemplate struct {
+	Key      string   `toml:"key"`       // Key that picks the template
+	Name     string   `toml:"name"`      // Name shown in the picker and used with -t
+	File     string   `toml:"file"`      // Target file, relative to the org file's directory; the current file if empty
+	Heading  []string `toml:"heading"`   // Headings to file the entry under, created if missing
+	DateTree bool     `toml:"date_tree"` // File the entry under year, month and day headings below the heading
+	Template string   `toml:"template"`  // Entry text; just the captured text if empty
+	State    string   `toml:"state"`     // State of the entry, or "none"; the default new task state if empty
+	Tags     []string `toml:"tags"`
+	Priority string   `toml:"priority"`
+	Effort   string   `toml:"effort"`
+}
+
+// defaultDeadlineWarningDays is how many days before a deadline it shows up by default
+const defaultDeadlineWarningDays = 14
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
+	deadlineWarningDays := defaultDeadlineWarningDays
 	return &Config{
 		Keybindings: KeybindingsConfig{
 			Up:            []string{"up", "k"},
@@ -132,6 +209,9 @@ func DefaultConfig() *Config {
 			Quit:          []string{"q", "ctrl+c"},
 			Settings:      []string{","},
 			TagItem:       []string{"#"},
+			ShowHistory:   []string{"H"},
+			Export:        []string{"x"},
+			Pomodoro:      []string{"P"},
 		},
 		Colors: ColorsConfig{
 			Todo:      "202",
@@ -142,6 +222,8 @@ func DefaultConfig() *Config {
 			Title:     "99",
 			Scheduled: "141",
 			Overdue:   "196",
+			DueToday:  "208",
+			Warning:   "220",
 			Status:    "241",
 			Note:      "246",
 			Folded:    "243",
@@ -172,6 +254,20 @@ func DefaultConfig() *Config {
 			OrgSyntaxHighlighting: true,
 			ShowIndentationGuides: true,
 			IndentationGuideColor: "245",
+			HabitDaysBefore:       21,
+			HabitDaysAfter:        7,
+			DeadlineWarningDays:   &deadlineWarningDays,
+		},
+		Hooks: HooksConfig{
+			Timeout: 10,
+		},
+		Notify: NotifyConfig{
+			MinutesBefore: 10,
+			DeadlineTime:  "09:00",
+		},
+		Pomodoro: PomodoroConfig{
+			WorkMinutes:  25,
+			BreakMinutes: 5,
 		},
 	}
 }
@@ -213,6 +309,9 @@ func LoadConfig() (*Config, error) {
 
 	// Merge with defaults for any missing values
 	config.fillDefaults()
+	if err := config.checkCaptureTemplates(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
@@ -335,6 +434,15 @@ func (c *Config) fillDefaults() {
 	if len(c.Keybindings.TagItem) == 0 {
 		c.Keybindings.TagItem = defaults.Keybindings.TagItem
 	}
+	if len(c.Keybindings.ShowHistory) == 0 {
+		c.Keybindings.ShowHistory = defaults.Keybindings.ShowHistory
+	}
+	if len(c.Keybindings.Export) == 0 {
+		c.Keybindings.Export = defaults.Keybindings.Export
+	}
+	if len(c.Keybindings.Pomodoro) == 0 {
+		c.Keybindings.Pomodoro = defaults.Keybindings.Pomodoro
+	}
 
 	// Fill colors if empty
 	if c.Colors.Todo == "" {
@@ -361,6 +469,12 @@ func (c *Config) fillDefaults() {
 	if c.Colors.Overdue == "" {
 		c.Colors.Overdue = defaults.Colors.Overdue
 	}
+	if c.Colors.DueToday == "" {
+		c.Colors.DueToday = defaults.Colors.DueToday
+	}
+	if c.Colors.Warning == "" {
+		c.Colors.Warning = defaults.Colors.Warning
+	}
 	if c.Colors.Status == "" {
 		c.Colors.Status = defaults.Colors.Status
 	}
@@ -401,6 +515,36 @@ func (c *Config) fillDefaults() {
 	if c.UI.IndentationGuideColor == "" {
 		c.UI.IndentationGuideColor = defaults.UI.IndentationGuideColor
 	}
+	if c.UI.HabitDaysBefore == 0 {
+		c.UI.HabitDaysBefore = defaults.UI.HabitDaysBefore
+	}
+	if c.UI.HabitDaysAfter == 0 {
+		c.UI.HabitDaysAfter = defaults.UI.HabitDaysAfter
+	}
+	if c.UI.DeadlineWarningDays == nil {
+		c.UI.DeadlineWarningDays = defaults.UI.DeadlineWarningDays
+	}
+
+	// Fill hooks if zero values
+	if c.Hooks.Timeout == 0 {
+		c.Hooks.Timeout = defaults.Hooks.Timeout
+	}
+
+	// Fill notify if zero values
+	if c.Notify.MinutesBefore == 0 {
+		c.Notify.MinutesBefore = defaults.Notify.MinutesBefore
+	}
+	if c.Notify.DeadlineTime == "" {
+		c.Notify.DeadlineTime = defaults.Notify.DeadlineTime
+	}
+
+	// Fill pomodoro if zero values
+	if c.Pomodoro.WorkMinutes == 0 {
+		c.Pomodoro.WorkMinutes = defaults.Pomodoro.WorkMinutes
+	}
+	if c.Pomodoro.BreakMinutes == 0 {
+		c.Pomodoro.BreakMinutes = defaults.Pomodoro.BreakMinutes
+	}
 }
 
 // BuildKeyBinding creates a key.Binding from config
@@ -542,6 +686,12 @@ func (c *Config) UpdateKeybinding(action string, keys []string) error {
 		c.Keybindings.Help = keys
 	case "quit":
 		c.Keybindings.Quit = keys
+	case "show_history":
+		c.Keybindings.ShowHistory = keys
+	case "export":
+		c.Keybindings.Export = keys
+	case "pomodoro":
+		c.Keybindings.Pomodoro = keys
 	default:
 		return fmt.Errorf("unknown action: %s", action)
 	}
@@ -579,6 +729,9 @@ func (c *Config) GetAllKeybindings() map[string][]string {
 		"quit":           c.Keybindings.Quit,
 		"settings":       c.Keybindings.Settings,
 		"tag_item":       c.Keybindings.TagItem,
+		"show_history":   c.Keybindings.ShowHistory,
+		"export":         c.Keybindings.Export,
+		"pomodoro":       c.Keybindings.Pomodoro,
 	}
 }
 
@@ -604,3 +757,131 @@ func (c *Config) GetDefaultNewTaskState() string {
 
 	return ""
 }
+
+// IsDoneState returns true if the given state is the final (done) state
+// The last configured state is treated as the done state throughout the app
+func (c *Config) IsDoneState(stateName string) bool {
+	if stateName == "" || len(c.States.States) == 0 {
+		return false
+	}
+	return c.States.States[len(c.States.States)-1].Name == stateName
+}
+
+// GetDoneState returns the name of the done state, or empty string if no states are configured
+func (c *Config) GetDoneState() string {
+	if len(c.States.States) == 0 {
+		return ""
+	}
+	return c.States.States[len(c.States.States)-1].Name
+}
+
+// Location returns the timezone dates in org files are in: the configured one, or the
+// system's if none is set
+func (c *Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", c.Timezone)
+	}
+	return loc, nil
+}
+
+// Zone returns the timezone dates in org files are in, falling back to the system's if
+// the configured one is unknown. Location reports why it is.
+func (c *Config) Zone() *time.Location {
+	loc, err := c.Location()
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Now returns the current time in the timezone dates are in, so that it is written and
+// compared as a wall clock time in that timezone
+func (c *Config) Now() time.Time {
+	return time.Now().In(c.Zone())
+}
+
+// GetDeadlineWarningDays returns how many days before a deadline it starts showing up as
+// approaching. 0 means only on the day it is due.
+func (c *Config) GetDeadlineWarningDays() int {
+	if c.UI.DeadlineWarningDays == nil {
+		return defaultDeadlineWarningDays
+	}
+	return max(*c.UI.DeadlineWarningDays, 0)
+}
+
+// GetCaptureTemplate returns the capture template with the given key or name, or nil if
+// there is none. An empty name matches nothing, not templates without a name.
+func (c *Config) GetCaptureTemplate(name string) *CaptureTemplate {
+	if name == "" {
+		return nil
+	}
+	for i := range c.Capture.Templates {
+		tmpl := &c.Capture.Templates[i]
+		if tmpl.Key == name || strings.EqualFold(tmpl.Name, name) {
+			return tmpl
+		}
+	}
+	return nil
+}
+
+// checkCaptureTemplates reports capture templates that give a state which isn't configured
+func (c *Config) checkCaptureTemplates() error {
+	states := c.GetStateNames()
+	for _, tmpl := range c.Capture.Templates {
+		known := tmpl.State == "" || strings.EqualFold(tmpl.State, "none")
+		for _, state := range states {
+			if tmpl.State == state {
+				known = true
+			}
+		}
+		if known {
+			continue
+		}
+		name := tmpl.Name
+		if name == "" {
+			name = tmpl.Key
+		}
+		return fmt.Errorf("capture template %q has unknown state %q (states are %s)", name, tmpl.State, strings.Join(states, ", "))
+	}
+	return nil
+}
+
+// GetHook returns the command configured for an event, or an empty string if there is none
+func (c *Config) GetHook(event string) string {
+	switch event {
+	case "state_changed":
+		return c.Hooks.StateChanged
+	case "captured":
+		return c.Hooks.Captured
+	case "clock_in":
+		return c.Hooks.ClockIn
+	case "clock_out":
+		return c.Hooks.ClockOut
+	case "deadline_set":
+		return c.Hooks.DeadlineSet
+	case "deleted":
+		return c.Hooks.Deleted
+	case "saved":
+		return c.Hooks.Saved
+	case "reminder":
+		return c.Hooks.Reminder
+	case "pomodoro":
+		return c.Hooks.Pomodoro
+	}
+	return ""
+}
+
+// GetStateLogging returns whether entering the given state should be logged,
+// and whether the user should be prompted for a note
+func (c *Config) GetStateLogging(stateName string) (logChange bool, logNote bool) {
+	for _, state := range c.States.States {
+		if state.Name == stateName {
+			return state.Log || state.LogNote, state.LogNote
+		}
+	}
+	return false, false
+}
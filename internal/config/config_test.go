@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestDeadlineWarningDays(t *testing.T) {
+	tests := []struct {
+		toml string
+		want int
+	}{
+		{"", 14},
+		{"[ui]\ndeadline_warning_days = 7", 7},
+		{"[ui]\ndeadline_warning_days = 0", 0},
+	}
+	for _, tt := range tests {
+		var cfg Config
+		if _, err := toml.Decode(tt.toml, &cfg); err != nil {
+			t.Fatal(err)
+		}
+		cfg.fillDefaults()
+		if got := cfg.GetDeadlineWarningDays(); got != tt.want {
+			t.Errorf("deadline warning days for %q = %d; want %d", tt.toml, got, tt.want)
+		}
+	}
+}
+
+func TestCaptureTemplateStates(t *testing.T) {
+	tests := []struct {
+		state   string
+		wantErr bool
+	}{
+		{"", false},
+		{"none", false},
+		{"TODO", false},
+		{"DONE", false},
+		{"WAITING", true},
+		{"todo", true},
+	}
+	for _, tt := range tests {
+		var cfg Config
+		if _, err := toml.Decode("[[capture.templates]]\nkey = \"t\"\nstate = \""+tt.state+"\"", &cfg); err != nil {
+			t.Fatal(err)
+		}
+		cfg.fillDefaults()
+		if err := cfg.checkCaptureTemplates(); (err != nil) != tt.wantErr {
+			t.Errorf("template with state %q: error = %v; want error %v", tt.state, err, tt.wantErr)
+		}
+	}
+}
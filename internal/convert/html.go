@@ -0,0 +1,450 @@
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+	"github.com/rwejlgaard/org/internal/render"
+)
+
+// HTMLOptions controls an HTML export
+type HTMLOptions struct {
+	Title    string // Page title
+	SkipDone bool   // Leave out items in the done state, with their children
+}
+
+// Priority badge colors, matching the TUI
+var htmlPriorityColors = map[model.Priority]string{
+	model.PriorityA: "196",
+	model.PriorityB: "214",
+	model.PriorityC: "226",
+}
+
+// cssColorPattern matches the hex colors and color names that are passed to CSS as they are
+var cssColorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|[a-zA-Z]+)$`)
+
+// htmlLinkSchemes are the URL schemes links may use; links without a scheme are relative
+var htmlLinkSchemes = map[string]bool{"http": true, "https": true, "mailto": true, "file": true}
+
+// inlineMathPattern matches inline LaTeX like \(x^2\), \[x^2\] or $$x^2$$
+var inlineMathPattern = regexp.MustCompile(`\\\((.+?)\\\)|\\\[(.+?)\\\]|\$\$(.+?)\$\$`)
+
+// htmlEmphasis maps org inline markup to HTML, applied after escaping
+var htmlEmphasis = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`(^|[\s(])[=~]([^\s=~](?:[^=~]*[^\s=~])?)[=~]($|[\s.,;:!?)])`), "$1<code>$2</code>$3"},
+	{regexp.MustCompile(`(^|[\s(])\*([^\s*](?:[^*]*[^\s*])?)\*($|[\s.,;:!?)])`), "$1<b>$2</b>$3"},
+	{regexp.MustCompile(`(^|[\s(])/([^\s/](?:[^/]*[^\s/])?)/($|[\s.,;:!?)])`), "$1<i>$2</i>$3"},
+	{regexp.MustCompile(`(^|[\s(])\+([^\s+](?:[^+]*[^\s+])?)\+($|[\s.,;:!?)])`), "$1<del>$2</del>$3"},
+}
+
+const htmlStyle = `
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; color: #222; line-height: 1.5; }
+header { display: flex; align-items: baseline; justify-content: space-between; gap: 1rem; }
+header button { font: inherit; font-size: 0.85rem; padding: 0.2rem 0.6rem; cursor: pointer; }
+details { margin: 0.2rem 0 0.2rem 0; }
+details details { margin-left: 1.4rem; }
+summary { cursor: pointer; padding: 0.15rem 0; }
+summary .title { font-weight: 600; }
+.done > summary .title { text-decoration: line-through; color: #888; }
+.badge { display: inline-block; border-radius: 0.25rem; padding: 0 0.4rem; font-size: 0.75rem; font-weight: 700; vertical-align: middle; }
+.tag { font-weight: 400; border-radius: 1rem; }
+.dates { font-size: 0.85rem; color: #666; margin-left: 1.4rem; }
+.dates .overdue { color: #c00; font-weight: 600; }
+.body { margin-left: 1.4rem; }
+.body pre { padding: 0.6rem; border-radius: 0.3rem; overflow-x: auto; }
+.body pre.plain { background: #f4f4f4; }
+.body table { border-collapse: collapse; }
+.body th, .body td { border: 1px solid #ccc; padding: 0.2rem 0.6rem; }
+.body blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1rem; color: #555; }
+.math { font-family: "Cambria Math", "STIX Two Math", serif; font-style: italic; }
+div.math { text-align: center; margin: 0.5rem 0; }
+`
+
+const htmlScript = `
+function setAll(open) { document.querySelectorAll("details").forEach(function (d) { d.open = open; }); }
+`
+
+// ExportHTML writes items as a single self-contained HTML page with collapsible headings
+func ExportHTML(w io.Writer, items []*model.Item, cfg *config.Config, opts HTMLOptions) error {
+	bw := bufio.NewWriter(w)
+	title := html.EscapeString(opts.Title)
+
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(bw, "<title>%s</title>\n<style>%s</style>\n<script>%s</script>\n</head>\n<body>\n", title, htmlStyle, htmlScript)
+	fmt.Fprintf(bw, "<header><h1>%s</h1><div><button onclick=\"setAll(true)\">Expand all</button> <button onclick=\"setAll(false)\">Collapse all</button></div></header>\n", title)
+
+	now := cfg.Now()
+	var walk func(items []*model.Item)
+	walk = func(items []*model.Item) {
+		for _, item := range items {
+			done := cfg.IsDoneState(string(item.State))
+			if opts.SkipDone && done {
+				continue
+			}
+
+			class := "item"
+			if done {
+				class += " done"
+			}
+			// Open items start expanded, done items collapsed
+			open := " open"
+			if done {
+				open = ""
+			}
+			fmt.Fprintf(bw, "<details class=\"%s\"%s>\n<summary>%s</summary>\n", class, open, htmlHeading(item, cfg))
+			writeHTMLDates(bw, item, now, done)
+			if body := htmlNotes(parser.StripMetadata(item.Notes)); body != "" {
+				fmt.Fprintf(bw, "<div class=\"body\">\n%s</div>\n", body)
+			}
+			walk(item.Children)
+			bw.WriteString("</details>\n")
+		}
+	}
+	walk(items)
+
+	fmt.Fprintf(bw, "<footer class=\"dates\">Exported %s</footer>\n</body>\n</html>\n", html.EscapeString(parser.FormatOrgDateTime(now)))
+	return bw.Flush()
+}
+
+// htmlHeading renders the summary line of an item with its badges
+func htmlHeading(item *model.Item, cfg *config.Config) string {
+	var parts []string
+	if item.State != model.StateNone {
+		parts = append(parts, htmlBadge(string(item.State), cfg.GetStateColor(string(item.State)), "state"))
+	}
+	if item.Priority != model.PriorityNone {
+		parts = append(parts, htmlBadge("#"+string(item.Priority), htmlPriorityColors[item.Priority], "priority"))
+	}
+	parts = append(parts, "<span class=\"title\">"+htmlInline(item.Title)+"</span>")
+	for _, tag := range item.Tags {
+		parts = append(parts, htmlBadge(tag, cfg.GetTagColor(tag), "tag"))
+	}
+	return strings.Join(parts, " ")
+}
+
+// htmlBadge renders a label on a colored background
+func htmlBadge(label, color, class string) string {
+	background := cssColor(color)
+	return fmt.Sprintf("<span class=\"badge %s\" style=\"background:%s;color:%s\">%s</span>",
+		class, background, contrastColor(background), html.EscapeString(label))
+}
+
+// writeHTMLDates writes the planning dates and effort below a heading
+func writeHTMLDates(w *bufio.Writer, item *model.Item, now time.Time, done bool) {
+	var dates []string
+	if item.Scheduled != nil {
+		dates = append(dates, "Scheduled: "+html.EscapeString(parser.FormatPlanningTimestamp(item.ScheduledTimestamp())))
+	}
+	if item.Deadline != nil {
+		deadline := "Deadline: " + html.EscapeString(parser.FormatPlanningTimestamp(item.DeadlineTimestamp()))
+		last := item.Deadline
+		if item.DeadlineEnd != nil {
+			last = item.DeadlineEnd
+		}
+		if !done && model.DaysUntil(now, *last) < 0 {
+			deadline = "<span class=\"overdue\">" + deadline + "</span>"
+		}
+		dates = append(dates, deadline)
+	}
+	if item.Closed != nil {
+		dates = append(dates, "Closed: "+html.EscapeString(parser.FormatOrgDateTime(*item.Closed)))
+	}
+	if item.Effort != "" {
+		dates = append(dates, "Effort: "+html.EscapeString(item.Effort))
+	}
+	if len(dates) > 0 {
+		fmt.Fprintf(w, "<div class=\"dates\">%s</div>\n", strings.Join(dates, " · "))
+	}
+}
+
+// htmlList is a list that is being written
+type htmlList struct {
+	indent int
+	tag    string // ul or ol
+}
+
+// htmlNotes converts notes to HTML: paragraphs, lists, tables, quotes and code blocks
+func htmlNotes(notes []string) string {
+	notes = dedent(notes)
+
+	var b strings.Builder
+	var paragraph, table, code []string
+	var lists []htmlList // Open lists, innermost last
+	block := ""          // Kind of block being collected: SRC, EXAMPLE, QUOTE or FENCE
+	language := ""
+
+	closeParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + strings.Join(paragraph, "\n") + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeLists := func(indent int) {
+		for len(lists) > 0 && lists[len(lists)-1].indent >= indent {
+			b.WriteString("</" + lists[len(lists)-1].tag + ">\n")
+			lists = lists[:len(lists)-1]
+		}
+	}
+	closeTable := func() {
+		if len(table) > 0 {
+			b.WriteString(htmlTable(table))
+			table = nil
+		}
+	}
+
+	for _, note := range notes {
+		trimmed := strings.TrimSpace(note)
+
+		if block != "" {
+			if (block == "FENCE" && strings.HasPrefix(trimmed, "```")) || (block != "FENCE" && orgBlockEnd.MatchString(trimmed)) {
+				b.WriteString(htmlBlock(block, language, code))
+				block, language, code = "", "", nil
+			} else {
+				code = append(code, note)
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(trimmed, "|") {
+			closeTable()
+		}
+
+		switch {
+		case trimmed == "":
+			closeParagraph()
+			closeLists(0)
+
+		case strings.HasPrefix(trimmed, "```"):
+			closeParagraph()
+			closeLists(0)
+			block, language = "FENCE", strings.ToLower(strings.TrimPrefix(trimmed, "```"))
+
+		case orgBlockStart.MatchString(trimmed):
+			closeParagraph()
+			closeLists(0)
+			matches := orgBlockStart.FindStringSubmatch(trimmed)
+			block, language = strings.ToUpper(matches[1]), strings.ToLower(matches[2])
+
+		case strings.HasPrefix(trimmed, "|"):
+			closeParagraph()
+			closeLists(0)
+			table = append(table, trimmed)
+
+		case orgCheckboxPattern.MatchString(note) || orgBulletPattern.MatchString(note) || orgNumberedPattern.MatchString(note):
+			closeParagraph()
+			indent := len(note) - len(strings.TrimLeft(note, " \t"))
+			closeLists(indent + 1)
+			tag := "ul"
+			if orgNumberedPattern.MatchString(note) {
+				tag = "ol"
+			}
+			if len(lists) == 0 || lists[len(lists)-1].indent < indent {
+				b.WriteString("<" + tag + ">\n")
+				lists = append(lists, htmlList{indent: indent, tag: tag})
+			}
+			b.WriteString("<li>" + htmlListItem(note) + "</li>\n")
+
+		default:
+			closeLists(0)
+			paragraph = append(paragraph, htmlInline(trimmed))
+		}
+	}
+	closeParagraph()
+	closeLists(0)
+	closeTable()
+	if block != "" {
+		b.WriteString(htmlBlock(block, language, code))
+	}
+
+	return b.String()
+}
+
+// htmlListItem renders the content of a list item, with a checkbox if it has one
+func htmlListItem(line string) string {
+	if matches := orgCheckboxPattern.FindStringSubmatch(line); matches != nil {
+		checked := ""
+		if matches[2] == "x" || matches[2] == "X" {
+			checked = " checked"
+		}
+		return "<input type=\"checkbox\" disabled" + checked + "> " + htmlInline(matches[3])
+	}
+	if matches := orgBulletPattern.FindStringSubmatch(line); matches != nil {
+		return htmlInline(matches[2])
+	}
+	return htmlInline(orgNumberedPattern.FindStringSubmatch(line)[3])
+}
+
+// htmlBlock renders a code, example or quote block. Code is highlighted with the same
+// highlighter as the TUI, and LaTeX blocks are rendered as math.
+func htmlBlock(kind, language string, lines []string) string {
+	switch {
+	case kind == "QUOTE":
+		var quoted []string
+		for _, line := range lines {
+			quoted = append(quoted, htmlInline(strings.TrimSpace(line)))
+		}
+		return "<blockquote>" + strings.Join(quoted, "<br>\n") + "</blockquote>\n"
+
+	case language == "latex":
+		var b strings.Builder
+		for _, line := range lines {
+			if strings.TrimSpace(line) != "" {
+				b.WriteString("<div class=\"math\">" + html.EscapeString(render.RenderLatexMath(line)) + "</div>\n")
+			}
+		}
+		return b.String()
+
+	case kind == "EXAMPLE" || language == "":
+		return "<pre class=\"plain\">" + html.EscapeString(strings.Join(lines, "\n")) + "</pre>\n"
+	}
+
+	var b strings.Builder
+	formatter := chromahtml.New(chromahtml.WithClasses(false), chromahtml.TabWidth(4))
+	if err := render.Highlight(&b, strings.Join(lines, "\n"), language, formatter); err != nil {
+		return "<pre class=\"plain\">" + html.EscapeString(strings.Join(lines, "\n")) + "</pre>\n"
+	}
+	return b.String() + "\n"
+}
+
+// htmlTable renders an org table, using the rows above the first rule as the header
+func htmlTable(rows []string) string {
+	headerRows := 0
+	for i, row := range rows {
+		if orgTableRule.MatchString(row) {
+			headerRows = i
+			break
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("<table>\n")
+	for i, row := range rows {
+		if orgTableRule.MatchString(row) {
+			continue
+		}
+		cell := "td"
+		if i < headerRows {
+			cell = "th"
+		}
+		b.WriteString("<tr>")
+		for _, value := range strings.Split(strings.Trim(row, "|"), "|") {
+			b.WriteString("<" + cell + ">" + htmlInline(strings.TrimSpace(value)) + "</" + cell + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
+
+// htmlInline escapes text and converts org links, emphasis and inline math
+func htmlInline(text string) string {
+	// Pull out links and math first so their contents aren't treated as markup
+	var placeholders []string
+	hold := func(s string) string {
+		placeholders = append(placeholders, s)
+		return "\x00" + strconv.Itoa(len(placeholders)-1) + "\x00"
+	}
+
+	text = orgLinkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		matches := orgLinkPattern.FindStringSubmatch(link)
+		target := strings.TrimPrefix(matches[1], "file:")
+		description := matches[2]
+		if description == "" {
+			description = target
+		}
+		if !safeLinkTarget(target) {
+			return hold(html.EscapeString(description))
+		}
+		return hold("<a href=\"" + html.EscapeString(target) + "\">" + html.EscapeString(description) + "</a>")
+	})
+	text = inlineMathPattern.ReplaceAllStringFunc(text, func(math string) string {
+		return hold("<span class=\"math\">" + html.EscapeString(render.RenderLatexMath(math)) + "</span>")
+	})
+
+	text = html.EscapeString(text)
+	for _, e := range htmlEmphasis {
+		text = e.pattern.ReplaceAllString(text, e.replace)
+	}
+
+	for i, p := range placeholders {
+		text = strings.Replace(text, "\x00"+strconv.Itoa(i)+"\x00", p, 1)
+	}
+	return text
+}
+
+// safeLinkTarget reports whether a link target can be used as an href: a relative link
+// or one with an allowed scheme, so that links like javascript: don't run in the page
+func safeLinkTarget(target string) bool {
+	scheme, _, found := strings.Cut(target, ":")
+	if !found || strings.ContainsAny(scheme, "/?#") {
+		return true
+	}
+	// Browsers ignore whitespace and control characters in the scheme
+	scheme = strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, scheme)
+	return htmlLinkSchemes[strings.ToLower(scheme)]
+}
+
+// cssColor converts a configured color (an ANSI 256 color number, a hex color or a color
+// name) to CSS. Anything else becomes gray, so config values can't add to the style.
+func cssColor(color string) string {
+	n, err := strconv.Atoi(color)
+	if err != nil || n < 0 || n > 255 {
+		if cssColorPattern.MatchString(color) {
+			return color
+		}
+		return "#808080"
+	}
+
+	// The 16 system colors, as xterm draws them
+	system := []string{
+		"#000000", "#800000", "#008000", "#808000", "#000080", "#800080", "#008080", "#c0c0c0",
+		"#808080", "#ff0000", "#00ff00", "#ffff00", "#0000ff", "#ff00ff", "#00ffff", "#ffffff",
+	}
+	switch {
+	case n < 16:
+		return system[n]
+	case n < 232:
+		// 6x6x6 color cube
+		levels := []int{0, 95, 135, 175, 215, 255}
+		n -= 16
+		return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[(n/6)%6], levels[n%6])
+	default:
+		// Grayscale ramp
+		gray := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+	}
+}
+
+// contrastColor picks black or white text for a hex background color
+func contrastColor(background string) string {
+	if len(background) != 7 || background[0] != '#' {
+		return "#fff"
+	}
+	rgb, err := strconv.ParseUint(background[1:], 16, 32)
+	if err != nil {
+		return "#fff"
+	}
+	r, g, b := float64(rgb>>16&0xff), float64(rgb>>8&0xff), float64(rgb&0xff)
+	if 0.299*r+0.587*g+0.114*b > 150 {
+		return "#000"
+	}
+	return "#fff"
+}
@@ -0,0 +1,263 @@
+package convert
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// ICSOptions controls what is included in an iCalendar export
+type ICSOptions struct {
+	IncludeClock bool // Export clock entries as events
+}
+
+// ICS date formats. Times are written in UTC, so that calendars in other timezones show
+// them at the right time, but floating local times are read too.
+const (
+	icsDateFormat     = "20060102"
+	icsDateTimeFormat = "20060102T150405"
+	icsUTCFormat      = "20060102T150405Z"
+)
+
+// icsPriorities maps org priorities to iCalendar priorities (1 is highest)
+var icsPriorities = map[model.Priority]int{
+	model.PriorityA: 1,
+	model.PriorityB: 5,
+	model.PriorityC: 9,
+}
+
+// icsFrequencies maps repeater units to RRULE frequencies
+var icsFrequencies = map[string]string{
+	"h": "HOURLY",
+	"d": "DAILY",
+	"w": "WEEKLY",
+	"m": "MONTHLY",
+	"y": "YEARLY",
+}
+
+// icsWriter writes iCalendar content lines, folding and terminating them with CRLF
+type icsWriter struct {
+	w   *bufio.Writer
+	now time.Time
+}
+
+// line writes a content line, folding it at 75 octets as required by RFC 5545
+func (iw *icsWriter) line(format string, args ...interface{}) {
+	s := fmt.Sprintf(format, args...)
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		// Don't split multi-byte UTF-8 sequences
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		iw.w.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts towards the limit
+		limit = 74
+	}
+	iw.w.WriteString(s + "\r\n")
+}
+
+// ExportICS writes the dated items and optionally the clock entries of an org file as an iCalendar feed.
+// Each SCHEDULED and DEADLINE date becomes a VEVENT, and each item with a TODO state and a date
+// also becomes a VTODO.
+func ExportICS(w io.Writer, orgFile *model.OrgFile, cfg *config.Config, opts ICSOptions) error {
+	iw := &icsWriter{w: bufio.NewWriter(w), now: time.Now().UTC()}
+
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:-//rwejlgaard//org//EN")
+	iw.line("CALSCALE:GREGORIAN")
+	iw.line("X-WR-CALNAME:%s", icsEscape(strings.TrimSuffix(filepath.Base(orgFile.Path), ".org")))
+
+	var walk func(items []*model.Item, path []string)
+	walk = func(items []*model.Item, path []string) {
+		seen := make(map[string]int)
+		for _, item := range items {
+			// Siblings with the same title are told apart by how many came before them
+			itemPath := append(append([]string{}, path...), fmt.Sprintf("%s\x00%d", item.Title, seen[item.Title]))
+			seen[item.Title]++
+			uid := icsUID(orgFile, item, itemPath)
+
+			if item.Scheduled != nil {
+				iw.writeEvent("SC-"+uid, item.Title, item, item.ScheduledTimestamp())
+			}
+			if item.Deadline != nil {
+				iw.writeEvent("DL-"+uid, "DL: "+item.Title, item, item.DeadlineTimestamp())
+			}
+			if item.State != model.StateNone && (item.Scheduled != nil || item.Deadline != nil) {
+				iw.writeTodo("TODO-"+uid, item, cfg)
+			}
+			if opts.IncludeClock {
+				for _, entry := range item.ClockEntries {
+					if entry.End == nil {
+						continue
+					}
+					iw.line("BEGIN:VEVENT")
+					iw.line("UID:CLOCK-%d-%s", entry.Start.Unix(), uid)
+					iw.line("DTSTAMP:%s", iw.now.Format(icsUTCFormat))
+					iw.line("DTSTART:%s", entry.Start.UTC().Format(icsUTCFormat))
+					iw.line("DTEND:%s", entry.End.UTC().Format(icsUTCFormat))
+					iw.line("SUMMARY:%s", icsEscape(item.Title))
+					iw.writeCategories(item)
+					iw.line("END:VEVENT")
+				}
+			}
+
+			walk(item.Children, itemPath)
+		}
+	}
+	walk(orgFile.Items, nil)
+
+	iw.line("END:VCALENDAR")
+	return iw.w.Flush()
+}
+
+// writeEvent writes a VEVENT for a planning date and the optional end of its range. Dates
+// without a time of day become all-day events, and timed events without an end last an hour.
+func (iw *icsWriter) writeEvent(uid, summary string, item *model.Item, ts model.Timestamp) {
+	date, end := ts.Start, ts.End
+	iw.line("BEGIN:VEVENT")
+	iw.line("UID:%s", uid)
+	iw.line("DTSTAMP:%s", iw.now.Format(icsUTCFormat))
+	if ts.HasTime {
+		last := date.Add(time.Hour)
+		if end != nil {
+			last = *end
+		}
+		iw.line("DTSTART:%s", date.UTC().Format(icsUTCFormat))
+		iw.line("DTEND:%s", last.UTC().Format(icsUTCFormat))
+	} else {
+		last := date
+		if end != nil {
+			last = *end
+		}
+		iw.line("DTSTART;VALUE=DATE:%s", date.Format(icsDateFormat))
+		iw.line("DTEND;VALUE=DATE:%s", last.AddDate(0, 0, 1).Format(icsDateFormat))
+	}
+	if rrule := icsRRule(ts.Repeater); rrule != "" {
+		iw.line("RRULE:%s", rrule)
+	}
+	iw.line("SUMMARY:%s", icsEscape(summary))
+	iw.writeDetails(item)
+	iw.line("END:VEVENT")
+}
+
+// writeTodo writes a VTODO for an item with a TODO state
+func (iw *icsWriter) writeTodo(uid string, item *model.Item, cfg *config.Config) {
+	iw.line("BEGIN:VTODO")
+	iw.line("UID:%s", uid)
+	iw.line("DTSTAMP:%s", iw.now.Format(icsUTCFormat))
+	if item.Scheduled != nil {
+		iw.line("DTSTART%s", icsDateValue(*item.Scheduled, item.ScheduledHasTime))
+	}
+	if item.Deadline != nil {
+		iw.line("DUE%s", icsDateValue(*item.Deadline, item.DeadlineHasTime))
+	}
+	// A VTODO can only repeat once, prefer the date it is anchored on
+	if item.Scheduled != nil {
+		if rrule := icsRRule(item.ScheduledRepeater); rrule != "" {
+			iw.line("RRULE:%s", rrule)
+		}
+	} else if rrule := icsRRule(item.DeadlineRepeater); rrule != "" {
+		iw.line("RRULE:%s", rrule)
+	}
+	iw.line("SUMMARY:%s", icsEscape(item.Title))
+	if cfg.IsDoneState(string(item.State)) {
+		iw.line("STATUS:COMPLETED")
+		if item.Closed != nil {
+			iw.line("COMPLETED:%s", item.Closed.UTC().Format(icsUTCFormat))
+		}
+	} else {
+		iw.line("STATUS:NEEDS-ACTION")
+	}
+	iw.writeDetails(item)
+	iw.line("END:VTODO")
+}
+
+// writeDetails writes the priority, categories and description shared by events and todos
+func (iw *icsWriter) writeDetails(item *model.Item) {
+	if priority, ok := icsPriorities[item.Priority]; ok {
+		iw.line("PRIORITY:%d", priority)
+	}
+	iw.writeCategories(item)
+	if description := strings.TrimSpace(strings.Join(parser.StripMetadata(item.Notes), "\n")); description != "" {
+		iw.line("DESCRIPTION:%s", icsEscape(description))
+	}
+}
+
+// writeCategories writes the item's tags as categories
+func (iw *icsWriter) writeCategories(item *model.Item) {
+	if len(item.Tags) == 0 {
+		return
+	}
+	categories := make([]string, len(item.Tags))
+	for i, tag := range item.Tags {
+		categories[i] = icsEscape(tag)
+	}
+	iw.line("CATEGORIES:%s", strings.Join(categories, ","))
+}
+
+// icsUID returns a UID that stays the same across exports: the item's :ID: property if it
+// has one, otherwise a hash of its file and outline path
+func icsUID(orgFile *model.OrgFile, item *model.Item, path []string) string {
+	if id := item.GetProperty("ID"); id != "" {
+		return id
+	}
+	file := item.SourceFile
+	if file == "" {
+		file = orgFile.Path
+	}
+	sum := sha1.Sum([]byte(icsFilePath(file) + "\x00" + strings.Join(path, "\x00")))
+	return hex.EncodeToString(sum[:]) + "@org"
+}
+
+// icsFilePath returns the path of a file relative to the home directory, or its absolute
+// path outside of it, so that files with the same name in different directories get
+// different UIDs while the UIDs don't depend on the directory org is run from
+func icsFilePath(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(abs)
+}
+
+// icsRRule converts an org repeater cookie into an RRULE value
+func icsRRule(cookie string) string {
+	repeater, ok := model.ParseRepeater(cookie)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("FREQ=%s;INTERVAL=%d", icsFrequencies[repeater.Unit], repeater.Value)
+}
+
+// icsDateValue formats a date as a property value suffix, marking dates without a time of day
+func icsDateValue(t time.Time, hasTime bool) string {
+	if hasTime {
+		return ":" + t.UTC().Format(icsUTCFormat)
+	}
+	return ";VALUE=DATE:" + t.Format(icsDateFormat)
+}
+
+// icsEscape escapes text values as required by RFC 5545
+func icsEscape(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(s)
+}
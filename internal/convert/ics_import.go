@@ -0,0 +1,350 @@
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// icsUIDProperty is the property imported items keep their iCalendar UID in
+const icsUIDProperty = "ICAL_UID"
+
+// icsDurationPattern matches iCalendar durations such as PT1H30M or P2D
+var icsDurationPattern = regexp.MustCompile(`^[+-]?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// invalidTagChars matches characters that can't appear in org tags
+var invalidTagChars = regexp.MustCompile(`[^[:alnum:]_@#%]+`)
+
+// icsProperty is a single content line of an iCalendar component
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icsComponent is a VEVENT or VTODO with its properties, keyed by name
+type icsComponent struct {
+	Kind  string
+	Props map[string]icsProperty
+}
+
+// ImportICS adds the events and to-dos of an iCalendar file to an org file as top-level headings.
+// Entries whose UID was imported before are skipped. Returns the number of items added and skipped.
+func ImportICS(r io.Reader, orgFile *model.OrgFile, cfg *config.Config) (added int, skipped int, err error) {
+	components, err := parseICS(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Collect UIDs from earlier imports
+	seen := make(map[string]bool)
+	var collect func(items []*model.Item)
+	collect = func(items []*model.Item) {
+		for _, item := range items {
+			if uid := item.GetProperty(icsUIDProperty); uid != "" {
+				seen[uid] = true
+			}
+			collect(item.Children)
+		}
+	}
+	collect(orgFile.Items)
+
+	for _, component := range components {
+		uid := component.Props["UID"].Value
+		if uid != "" && seen[uid] {
+			skipped++
+			continue
+		}
+		seen[uid] = true
+
+		item, err := icsComponentToItem(component, cfg)
+		if err != nil {
+			return added, skipped, err
+		}
+		orgFile.Items = append(orgFile.Items, item)
+		added++
+	}
+
+	return added, skipped, nil
+}
+
+// icsComponentToItem converts an event or to-do into a heading
+func icsComponentToItem(component icsComponent, cfg *config.Config) (*model.Item, error) {
+	props := component.Props
+	item := &model.Item{
+		Level:      1,
+		Title:      strings.Join(strings.Fields(icsUnescape(props["SUMMARY"].Value)), " "),
+		Properties: make(map[string]string),
+	}
+	if item.Title == "" {
+		item.Title = "(no title)"
+	}
+
+	if component.Kind == "VTODO" {
+		if props["STATUS"].Value == "COMPLETED" {
+			item.State = model.TodoState(cfg.GetDoneState())
+		} else {
+			item.State = openState(cfg)
+		}
+	}
+
+	if priority, err := strconv.Atoi(props["PRIORITY"].Value); err == nil && priority > 0 {
+		switch {
+		case priority < 5:
+			item.Priority = model.PriorityA
+		case priority == 5:
+			item.Priority = model.PriorityB
+		default:
+			item.Priority = model.PriorityC
+		}
+	}
+
+	for _, category := range strings.Split(props["CATEGORIES"].Value, ",") {
+		if tag := invalidTagChars.ReplaceAllString(icsUnescape(strings.TrimSpace(category)), "_"); strings.Trim(tag, "_") != "" {
+			item.Tags = append(item.Tags, tag)
+		}
+	}
+
+	// Planning info all goes on one line right below the heading
+	var planning []string
+	if completed, ok := props["COMPLETED"]; ok && item.State != model.StateNone {
+		if t, _, err := parseICSTime(completed, cfg.Zone()); err == nil {
+			item.Closed = &t
+			planning = append(planning, "CLOSED: ["+parser.FormatOrgDateTime(t)+"]")
+		}
+	}
+	if dtstart, ok := props["DTSTART"]; ok {
+		start, allDay, err := parseICSTime(dtstart, cfg.Zone())
+		if err != nil {
+			return nil, err
+		}
+		end := icsEnd(props, start, allDay)
+		item.Scheduled = &start
+		item.ScheduledHasTime = !allDay
+		if end.After(start) {
+			item.ScheduledEnd = &end
+		}
+		item.ScheduledRepeater = icsRepeater(props["RRULE"].Value)
+
+		timestamp := parser.FormatTimestampRange(start, end, !allDay)
+		if item.ScheduledRepeater != "" {
+			// The repeater belongs in the first timestamp of a range
+			timestamp = strings.Replace(timestamp, ">", " "+item.ScheduledRepeater+">", 1)
+		}
+		planning = append(planning, "SCHEDULED: "+timestamp)
+	}
+	if due, ok := props["DUE"]; ok {
+		deadline, allDay, err := parseICSTime(due, cfg.Zone())
+		if err != nil {
+			return nil, err
+		}
+		item.Deadline = &deadline
+		item.DeadlineHasTime = !allDay
+		planning = append(planning, "DEADLINE: "+parser.FormatTimestampRange(deadline, deadline, !allDay))
+	}
+	if len(planning) > 0 {
+		item.Notes = append(item.Notes, strings.Join(planning, " "))
+	}
+
+	if uid := props["UID"].Value; uid != "" {
+		item.Properties[icsUIDProperty] = uid
+	}
+	if location := icsUnescape(props["LOCATION"].Value); location != "" {
+		item.Properties["LOCATION"] = strings.Join(strings.Fields(location), " ")
+	}
+	if organizer, ok := props["ORGANIZER"]; ok {
+		value := strings.TrimPrefix(strings.TrimPrefix(organizer.Value, "mailto:"), "MAILTO:")
+		if name := organizer.Params["CN"]; name != "" {
+			value = name + " <" + value + ">"
+		}
+		item.Properties["ORGANIZER"] = value
+	}
+	if len(item.Properties) > 0 {
+		item.Notes = append(item.Notes, ":PROPERTIES:")
+		for _, name := range []string{icsUIDProperty, "LOCATION", "ORGANIZER"} {
+			if value, ok := item.Properties[name]; ok {
+				item.Notes = append(item.Notes, ":"+name+": "+value)
+			}
+		}
+		item.Notes = append(item.Notes, ":END:")
+	}
+
+	if description := strings.TrimSpace(icsUnescape(props["DESCRIPTION"].Value)); description != "" {
+		for _, line := range strings.Split(description, "\n") {
+			line = strings.TrimRight(line, "\r ")
+			// Keep description lines from being read as headings
+			if strings.HasPrefix(line, "*") {
+				line = " " + line
+			}
+			item.Notes = append(item.Notes, line)
+		}
+	}
+
+	return item, nil
+}
+
+// icsEnd returns the end of an event from DTEND or DURATION. All-day events end on their last
+// day rather than the exclusive end date iCalendar uses.
+func icsEnd(props map[string]icsProperty, start time.Time, allDay bool) time.Time {
+	end := start
+	if dtend, ok := props["DTEND"]; ok {
+		if t, _, err := parseICSTime(dtend, start.Location()); err == nil {
+			end = t
+		}
+	} else if matches := icsDurationPattern.FindStringSubmatch(props["DURATION"].Value); matches != nil {
+		n := func(i int) int {
+			v, _ := strconv.Atoi(matches[i])
+			return v
+		}
+		end = start.AddDate(0, 0, 7*n(1)+n(2)).Add(time.Duration(n(3))*time.Hour + time.Duration(n(4))*time.Minute + time.Duration(n(5))*time.Second)
+	}
+	if allDay && end.After(start) {
+		end = end.AddDate(0, 0, -1)
+	}
+	return end
+}
+
+// icsRepeater converts a simple RRULE (FREQ and INTERVAL) into an org repeater cookie
+func icsRepeater(rrule string) string {
+	units := map[string]string{"HOURLY": "h", "DAILY": "d", "WEEKLY": "w", "MONTHLY": "m", "YEARLY": "y"}
+	var unit string
+	interval := 1
+	for _, part := range strings.Split(rrule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "FREQ":
+			unit = units[value]
+		case "INTERVAL":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				interval = n
+			}
+		}
+	}
+	if unit == "" {
+		return ""
+	}
+	return fmt.Sprintf("+%d%s", interval, unit)
+}
+
+// parseICSTime parses a DATE or DATE-TIME value into the time in loc, reporting whether it
+// is a date. Floating times without a timezone are taken to be in loc.
+func parseICSTime(p icsProperty, loc *time.Location) (time.Time, bool, error) {
+	if p.Params["VALUE"] == "DATE" || len(p.Value) == len(icsDateFormat) {
+		t, err := time.ParseInLocation(icsDateFormat, p.Value, loc)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(p.Value, "Z") {
+		t, err := time.Parse(icsUTCFormat, p.Value)
+		return t.In(loc), false, err
+	}
+
+	valueLoc := loc
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			valueLoc = l
+		}
+	}
+	t, err := time.ParseInLocation(icsDateTimeFormat, p.Value, valueLoc)
+	return t.In(loc), false, err
+}
+
+// parseICS reads the VEVENT and VTODO components of an iCalendar stream
+func parseICS(r io.Reader) ([]icsComponent, error) {
+	// Unfold continuation lines first
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+		} else if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var components []icsComponent
+	var current *icsComponent
+	depth := 0 // Nesting below the current component, e.g. VALARM
+	for _, line := range lines {
+		p := parseICSLine(line)
+		switch {
+		case p.Name == "BEGIN" && current == nil && (p.Value == "VEVENT" || p.Value == "VTODO"):
+			current = &icsComponent{Kind: p.Value, Props: make(map[string]icsProperty)}
+		case p.Name == "BEGIN" && current != nil:
+			depth++
+		case p.Name == "END" && current != nil && depth > 0:
+			depth--
+		case p.Name == "END" && current != nil:
+			components = append(components, *current)
+			current = nil
+		case current != nil && depth == 0:
+			// Only the first occurrence of a property is used
+			if _, ok := current.Props[p.Name]; !ok {
+				current.Props[p.Name] = p
+			}
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("unterminated %s", current.Kind)
+	}
+
+	return components, nil
+}
+
+// parseICSLine splits a content line like DTSTART;TZID=Europe/Oslo:20250106T140000
+func parseICSLine(line string) icsProperty {
+	p := icsProperty{Params: make(map[string]string)}
+
+	// The value starts at the first colon outside quoted parameter values
+	inQuotes := false
+	split := len(line)
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			split = i
+			break
+		}
+	}
+	if split < len(line) {
+		p.Value = line[split+1:]
+	}
+
+	parts := strings.Split(line[:split], ";")
+	p.Name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			p.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return p
+}
+
+// icsUnescape reverses icsEscape
+func icsUnescape(s string) string {
+	replacer := strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+	return replacer.Replace(s)
+}
+
+// openState returns the state imported tasks start in: the default new task state,
+// or the first state if new tasks have none
+func openState(cfg *config.Config) model.TodoState {
+	state := model.TodoState(cfg.GetDefaultNewTaskState())
+	if state == model.StateNone && len(cfg.GetStateNames()) > 0 {
+		state = model.TodoState(cfg.GetStateNames()[0])
+	}
+	return state
+}
@@ -0,0 +1,206 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// JSONSchemaVersion is the version of the JSON representation written by ExportJSON.
+// It is increased whenever fields change in a way older readers can't handle.
+const JSONSchemaVersion = 1
+
+// jsonFile is the top-level JSON document
+type jsonFile struct {
+	Version int        `json:"version"`
+	Path    string     `json:"path"`
+	Items   []jsonItem `json:"items"`
+}
+
+// jsonItem mirrors model.Item
+type jsonItem struct {
+	Level             int               `json:"level"`
+	State             string            `json:"state,omitempty"`
+	Priority          string            `json:"priority,omitempty"`
+	Title             string            `json:"title"`
+	Tags              []string          `json:"tags,omitempty"`
+	Scheduled         *time.Time        `json:"scheduled,omitempty"`
+	ScheduledEnd      *time.Time        `json:"scheduled_end,omitempty"`
+	ScheduledRepeater string            `json:"scheduled_repeater,omitempty"`
+	ScheduledDelay    string            `json:"scheduled_delay,omitempty"`
+	Deadline          *time.Time        `json:"deadline,omitempty"`
+	DeadlineEnd       *time.Time        `json:"deadline_end,omitempty"`
+	DeadlineRepeater  string            `json:"deadline_repeater,omitempty"`
+	DeadlineWarning   string            `json:"deadline_warning,omitempty"`
+	ScheduledHasTime  *bool             `json:"scheduled_has_time,omitempty"`
+	DeadlineHasTime   *bool             `json:"deadline_has_time,omitempty"`
+	Closed            *time.Time        `json:"closed,omitempty"`
+	Effort            string            `json:"effort,omitempty"`
+	Properties        map[string]string `json:"properties,omitempty"`
+	ClockEntries      []jsonClockEntry  `json:"clock_entries,omitempty"`
+	Notes             []string          `json:"notes,omitempty"`
+	Folded            bool              `json:"folded,omitempty"`
+	SourceFile        string            `json:"source_file,omitempty"`
+	Children          []jsonItem        `json:"children,omitempty"`
+}
+
+// jsonClockEntry mirrors model.ClockEntry
+type jsonClockEntry struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// ExportJSON writes the org file as a versioned JSON document
+func ExportJSON(w io.Writer, orgFile *model.OrgFile) error {
+	doc := jsonFile{Version: JSONSchemaVersion, Path: orgFile.Path, Items: []jsonItem{}}
+	for _, item := range orgFile.Items {
+		doc.Items = append(doc.Items, itemToJSON(item))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(doc)
+}
+
+// ImportJSON reads a document written by ExportJSON and adds its items to the org file,
+// or replaces the org file's items if replace is set. Returns the number of items added.
+func ImportJSON(r io.Reader, orgFile *model.OrgFile, cfg *config.Config, replace bool) (int, error) {
+	var doc jsonFile
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return 0, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if doc.Version < 1 || doc.Version > JSONSchemaVersion {
+		return 0, fmt.Errorf("unsupported schema version %d (expected %d)", doc.Version, JSONSchemaVersion)
+	}
+
+	// Convert everything first, so that an invalid document leaves the org file alone
+	var items []*model.Item
+	for _, j := range doc.Items {
+		item, err := itemFromJSON(j, 0, cfg.Zone())
+		if err != nil {
+			return 0, err
+		}
+		items = append(items, item)
+	}
+
+	if replace {
+		orgFile.Items = []*model.Item{}
+	}
+	orgFile.Items = append(orgFile.Items, items...)
+	return len(items), nil
+}
+
+// MarshalItem returns the JSON representation of a single item as used by ExportJSON,
+// without its children
+func MarshalItem(item *model.Item) ([]byte, error) {
+	copied := *item
+	copied.Children = nil
+	return json.Marshal(itemToJSON(&copied))
+}
+
+// itemToJSON converts an item and its children
+func itemToJSON(item *model.Item) jsonItem {
+	j := jsonItem{
+		Level:             item.Level,
+		State:             string(item.State),
+		Priority:          string(item.Priority),
+		Title:             item.Title,
+		Tags:              item.Tags,
+		Scheduled:         item.Scheduled,
+		ScheduledEnd:      item.ScheduledEnd,
+		ScheduledRepeater: item.ScheduledRepeater,
+		ScheduledDelay:    item.ScheduledDelay,
+		Deadline:          item.Deadline,
+		DeadlineEnd:       item.DeadlineEnd,
+		DeadlineRepeater:  item.DeadlineRepeater,
+		DeadlineWarning:   item.DeadlineWarning,
+		Closed:            item.Closed,
+		Effort:            item.Effort,
+		Properties:        item.Properties,
+		Notes:             item.Notes,
+		Folded:            item.Folded,
+		SourceFile:        item.SourceFile,
+	}
+	if item.Scheduled != nil {
+		j.ScheduledHasTime = &item.ScheduledHasTime
+	}
+	if item.Deadline != nil {
+		j.DeadlineHasTime = &item.DeadlineHasTime
+	}
+	for _, entry := range item.ClockEntries {
+		j.ClockEntries = append(j.ClockEntries, jsonClockEntry{Start: entry.Start, End: entry.End})
+	}
+	for _, child := range item.Children {
+		j.Children = append(j.Children, itemToJSON(child))
+	}
+	return j
+}
+
+// itemFromJSON converts a JSON item and its children back into the model, checking that
+// its level is below its parent's. The source file isn't kept, as the items are imported
+// into a single file.
+//
+// The notes still hold the planning lines and drawers as they were exported, so they
+// are brought in line with the fields, which are what an edited document changes.
+func itemFromJSON(j jsonItem, parentLevel int, loc *time.Location) (*model.Item, error) {
+	if j.Level <= parentLevel {
+		if parentLevel == 0 {
+			return nil, fmt.Errorf("item %q has level %d, levels start at 1", j.Title, j.Level)
+		}
+		return nil, fmt.Errorf("item %q has level %d, which isn't below its parent's level %d", j.Title, j.Level, parentLevel)
+	}
+
+	item := &model.Item{
+		Level:             j.Level,
+		State:             model.TodoState(j.State),
+		Priority:          model.Priority(j.Priority),
+		Title:             j.Title,
+		Tags:              j.Tags,
+		Scheduled:         j.Scheduled,
+		ScheduledEnd:      j.ScheduledEnd,
+		ScheduledRepeater: j.ScheduledRepeater,
+		ScheduledDelay:    j.ScheduledDelay,
+		Deadline:          j.Deadline,
+		DeadlineEnd:       j.DeadlineEnd,
+		DeadlineRepeater:  j.DeadlineRepeater,
+		DeadlineWarning:   j.DeadlineWarning,
+		Closed:            j.Closed,
+		Effort:            j.Effort,
+		Properties:        j.Properties,
+		Notes:             j.Notes,
+		Folded:            j.Folded,
+		ScheduledHasTime:  jsonHasTime(j.Scheduled, j.ScheduledHasTime),
+		DeadlineHasTime:   jsonHasTime(j.Deadline, j.DeadlineHasTime),
+	}
+	for _, entry := range j.ClockEntries {
+		item.ClockEntries = append(item.ClockEntries, model.ClockEntry{Start: entry.Start, End: entry.End})
+	}
+	parser.UpdatePlanning(item)
+	parser.UpdateProperties(item)
+	parser.UpdateClockLines(item)
+	parser.ScanTimestamps(item, loc)
+
+	for _, child := range j.Children {
+		childItem, err := itemFromJSON(child, j.Level, loc)
+		if err != nil {
+			return nil, err
+		}
+		item.Children = append(item.Children, childItem)
+	}
+	return item, nil
+}
+
+// jsonHasTime tells whether an imported date has a time of day. Documents written before
+// the *_has_time fields were added only give a time to dates that aren't at midnight.
+func jsonHasTime(date *time.Time, hasTime *bool) bool {
+	if hasTime != nil {
+		return *hasTime
+	}
+	return date != nil && hasTimeOfDay(*date)
+}
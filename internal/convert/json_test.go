@@ -0,0 +1,100 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+func TestImportJSONKeepsEditedFields(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Timezone = "UTC"
+	content := `* TODO Write report
+DEADLINE: <2025-01-10 Fri>
+:PROPERTIES:
+:EFFORT: 1:00
+:OWNER: sam
+:END:
+:LOGBOOK:
+CLOCK: [2025-01-06 Mon 09:00]--[2025-01-06 Mon 10:00] =>  1:00
+:END:
+Draft due <2025-01-08 Wed>
+`
+	orgFile, err := parser.Parse(strings.NewReader(content), "test.org", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var exported bytes.Buffer
+	if err := ExportJSON(&exported, orgFile); err != nil {
+		t.Fatal(err)
+	}
+	var doc jsonFile
+	if err := json.Unmarshal(exported.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// Edit the fields, leaving the notes as they were exported
+	item := &doc.Items[0]
+	deadline := time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)
+	item.Deadline = &deadline
+	item.Effort = "2:00"
+	item.Properties = map[string]string{"CONTEXT": "office"}
+	end := time.Date(2025, 1, 6, 11, 30, 0, 0, time.UTC)
+	item.ClockEntries[0].End = &end
+	edited, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "imported.org")
+	imported := &model.OrgFile{Path: path}
+	if _, err := ImportJSON(bytes.NewReader(edited), imported, cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(imported.Items[0].Timestamps); got != 1 {
+		t.Errorf("imported item has %d timestamps; want 1", got)
+	}
+	if err := parser.Save(imported); err != nil {
+		t.Fatal(err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(string(written), "* TODO Write report\nDEADLINE:") {
+		t.Errorf("saved file doesn't start with the heading and its planning line:\n%s", written)
+	}
+	for _, want := range []string{
+		"DEADLINE: <2025-01-17 Fri>",
+		":EFFORT: 2:00",
+		":CONTEXT: office",
+		"CLOCK: [2025-01-06 Mon 09:00]--[2025-01-06 Mon 11:30]",
+	} {
+		if !strings.Contains(string(written), want) {
+			t.Errorf("saved file doesn't contain %q:\n%s", want, written)
+		}
+	}
+	for _, stale := range []string{"2025-01-10", ":OWNER:", "10:00]"} {
+		if strings.Contains(string(written), stale) {
+			t.Errorf("saved file still contains %q:\n%s", stale, written)
+		}
+	}
+
+	reparsed, err := parser.ParseOrgFile(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reparsed.Items[0]; got.Effort != "2:00" || got.Deadline == nil || !got.Deadline.Equal(deadline) || len(got.ClockEntries) != 1 {
+		t.Errorf("reparsed item = effort %q, deadline %v, %d clock entries", got.Effort, got.Deadline, len(got.ClockEntries))
+	}
+}
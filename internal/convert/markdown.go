@@ -0,0 +1,333 @@
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// MarkdownOptions controls what is included in a Markdown export
+type MarkdownOptions struct {
+	SkipDone    bool // Leave out items in the done state, with their children
+	SkipDrawers bool // Leave out LOGBOOK and PROPERTIES drawers
+	SkipNotes   bool // Leave out notes, keeping only headings and dates
+}
+
+// Org markup patterns
+var (
+	orgLinkPattern     = regexp.MustCompile(`\[\[([^\]]+)\](?:\[([^\]]+)\])?\]`)
+	orgCheckboxPattern = regexp.MustCompile(`^(\s*)[-+*]\s+\[([ xX-])\]\s+(.*)$`)
+	orgBulletPattern   = regexp.MustCompile(`^(\s*)[-+]\s+(.*)$`)
+	orgNumberedPattern = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	orgTableRule       = regexp.MustCompile(`^\|[-+:]+\|?$`)
+	orgBlockStart      = regexp.MustCompile(`(?i)^#\+BEGIN_(SRC|EXAMPLE|QUOTE)(?:\s+(\S+))?`)
+	orgBlockEnd        = regexp.MustCompile(`(?i)^#\+END_(SRC|EXAMPLE|QUOTE)`)
+	orgDrawerStart     = regexp.MustCompile(`^:([A-Za-z]+):$`)
+)
+
+// orgEmphasis maps org inline markup to Markdown, applied in order
+var orgEmphasis = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`(^|[\s(])[=~]([^\s=~](?:[^=~]*[^\s=~])?)[=~]($|[\s.,;:!?)])`), "$1`$2`$3"},
+	{regexp.MustCompile(`(^|[\s(])\*([^\s*](?:[^*]*[^\s*])?)\*($|[\s.,;:!?)])`), "$1**$2**$3"},
+	{regexp.MustCompile(`(^|[\s(])/([^\s/](?:[^/]*[^\s/])?)/($|[\s.,;:!?)])`), "${1}_${2}_$3"},
+	{regexp.MustCompile(`(^|[\s(])\+([^\s+](?:[^+]*[^\s+])?)\+($|[\s.,;:!?)])`), "$1~~$2~~$3"},
+}
+
+// ExportMarkdown writes items and their children as GitHub-flavoured Markdown.
+// Heading levels are relative to the shallowest item, so a subtree starts at "#".
+func ExportMarkdown(w io.Writer, items []*model.Item, cfg *config.Config, opts MarkdownOptions) error {
+	bw := bufio.NewWriter(w)
+
+	minLevel := 0
+	for _, item := range items {
+		if minLevel == 0 || item.Level < minLevel {
+			minLevel = item.Level
+		}
+	}
+
+	first := true
+	var walk func(items []*model.Item)
+	walk = func(items []*model.Item) {
+		for _, item := range items {
+			if opts.SkipDone && cfg.IsDoneState(string(item.State)) {
+				continue
+			}
+			if !first {
+				bw.WriteString("\n")
+			}
+			first = false
+			writeMarkdownItem(bw, item, item.Level-minLevel+1, cfg, opts)
+			walk(item.Children)
+		}
+	}
+	walk(items)
+
+	return bw.Flush()
+}
+
+// writeMarkdownItem writes a single heading with its dates and notes
+func writeMarkdownItem(w *bufio.Writer, item *model.Item, level int, cfg *config.Config, opts MarkdownOptions) {
+	// Markdown only has six heading levels
+	if level > 6 {
+		level = 6
+	}
+
+	heading := strings.Repeat("#", level)
+	if item.State != model.StateNone {
+		heading += " **" + string(item.State) + "**"
+	}
+	if item.Priority != model.PriorityNone {
+		heading += " [#" + string(item.Priority) + "]"
+	}
+	title := markdownInline(item.Title)
+	if cfg.IsDoneState(string(item.State)) {
+		title = "~~" + title + "~~"
+	}
+	heading += " " + title
+	for _, tag := range item.Tags {
+		heading += " `" + tag + "`"
+	}
+	fmt.Fprintln(w, heading)
+
+	var dates []string
+	if item.Scheduled != nil {
+		dates = append(dates, "**Scheduled:** "+parser.FormatPlanningTimestamp(item.ScheduledTimestamp()))
+	}
+	if item.Deadline != nil {
+		dates = append(dates, "**Deadline:** "+parser.FormatPlanningTimestamp(item.DeadlineTimestamp()))
+	}
+	if item.Closed != nil {
+		dates = append(dates, "**Closed:** "+parser.FormatOrgDateTime(*item.Closed))
+	}
+	if item.Effort != "" {
+		dates = append(dates, "**Effort:** "+item.Effort)
+	}
+	if len(dates) > 0 {
+		fmt.Fprintf(w, "\n%s\n", strings.Join(dates, " · "))
+	}
+
+	if opts.SkipNotes {
+		return
+	}
+	lines := markdownNotes(item.Notes, opts)
+	if len(lines) > 0 {
+		fmt.Fprintf(w, "\n%s\n", strings.Join(lines, "\n"))
+	}
+}
+
+// markdownNotes converts an item's notes to Markdown lines
+func markdownNotes(notes []string, opts MarkdownOptions) []string {
+	// Planning lines are written below the heading and would throw off the indentation
+	var content []string
+	for _, note := range notes {
+		trimmed := strings.TrimSpace(note)
+		if !strings.HasPrefix(trimmed, "SCHEDULED:") && !strings.HasPrefix(trimmed, "DEADLINE:") && !strings.HasPrefix(trimmed, "CLOSED:") {
+			content = append(content, note)
+		}
+	}
+	notes = dedent(content)
+
+	var out []string
+	var table []string
+	inBlock := ""
+	inDrawer := ""
+	inFence := false // Markdown code fences are passed through as they are
+
+	// Tables, code blocks and HTML need blank lines around them to stand apart from paragraphs
+	blankLine := func() {
+		if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+			out = append(out, "")
+		}
+	}
+	flushTable := func() {
+		blankLine()
+		out = append(out, markdownTable(table)...)
+		out = append(out, "")
+		table = nil
+	}
+
+	for _, note := range notes {
+		trimmed := strings.TrimSpace(note)
+
+		if len(table) > 0 && !strings.HasPrefix(trimmed, "|") {
+			flushTable()
+		}
+
+		switch {
+		case inFence || (inBlock == "" && inDrawer == "" && strings.HasPrefix(trimmed, "```")):
+			if strings.HasPrefix(trimmed, "```") {
+				if !inFence {
+					blankLine()
+				}
+				inFence = !inFence
+			}
+			out = append(out, note)
+
+		case inBlock != "":
+			if orgBlockEnd.MatchString(trimmed) {
+				if inBlock != "QUOTE" {
+					out = append(out, "```")
+				} else {
+					out = append(out, "")
+				}
+				inBlock = ""
+			} else if inBlock == "QUOTE" {
+				out = append(out, strings.TrimRight("> "+markdownInline(trimmed), " "))
+			} else {
+				out = append(out, note)
+			}
+
+		case inDrawer != "":
+			if trimmed == ":END:" {
+				if !opts.SkipDrawers {
+					out = append(out, "```", "", "</details>", "")
+				}
+				inDrawer = ""
+			} else if !opts.SkipDrawers {
+				out = append(out, trimmed)
+			}
+
+		case orgDrawerStart.MatchString(trimmed) && trimmed != ":END:":
+			inDrawer = orgDrawerStart.FindStringSubmatch(trimmed)[1]
+			if !opts.SkipDrawers {
+				blankLine()
+				out = append(out, "<details><summary>"+inDrawer+"</summary>", "", "```")
+			}
+
+		case orgBlockStart.MatchString(trimmed):
+			matches := orgBlockStart.FindStringSubmatch(trimmed)
+			inBlock = strings.ToUpper(matches[1])
+			blankLine()
+			if inBlock == "SRC" {
+				out = append(out, "```"+matches[2])
+			} else if inBlock == "EXAMPLE" {
+				out = append(out, "```")
+			}
+
+		case strings.HasPrefix(trimmed, "|"):
+			table = append(table, trimmed)
+
+		case trimmed == "":
+			// Collapse runs of blank lines
+			if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+				out = append(out, "")
+			}
+
+		default:
+			out = append(out, markdownLine(note))
+		}
+	}
+	if len(table) > 0 {
+		flushTable()
+	}
+
+	// Drop blank lines left at either end
+	for len(out) > 0 && strings.TrimSpace(out[0]) == "" {
+		out = out[1:]
+	}
+	for len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// markdownLine converts a line of text, including list items and checkboxes
+func markdownLine(line string) string {
+	if matches := orgCheckboxPattern.FindStringSubmatch(line); matches != nil {
+		box := "[ ]"
+		if matches[2] == "x" || matches[2] == "X" {
+			box = "[x]"
+		}
+		return matches[1] + "- " + box + " " + markdownInline(matches[3])
+	}
+	if matches := orgBulletPattern.FindStringSubmatch(line); matches != nil {
+		return matches[1] + "- " + markdownInline(matches[2])
+	}
+	if matches := orgNumberedPattern.FindStringSubmatch(line); matches != nil {
+		return matches[1] + matches[2] + ". " + markdownInline(matches[3])
+	}
+	return markdownInline(strings.TrimLeft(line, " \t"))
+}
+
+// markdownInline converts org links and emphasis within a line
+func markdownInline(text string) string {
+	for _, e := range orgEmphasis {
+		text = e.pattern.ReplaceAllString(text, e.replace)
+	}
+	return orgLinkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		matches := orgLinkPattern.FindStringSubmatch(link)
+		target := strings.TrimPrefix(matches[1], "file:")
+		if matches[2] == "" {
+			return "<" + target + ">"
+		}
+		return "[" + matches[2] + "](" + target + ")"
+	})
+}
+
+// markdownTable converts an org table to a GFM table. GFM needs a header separator after the
+// first row, which is added if the org table has none.
+func markdownTable(rows []string) []string {
+	var cells [][]string
+	columns := 0
+	for _, row := range rows {
+		if orgTableRule.MatchString(row) {
+			continue
+		}
+		var rowCells []string
+		for _, cell := range strings.Split(strings.Trim(row, "|"), "|") {
+			rowCells = append(rowCells, markdownInline(strings.TrimSpace(cell)))
+		}
+		if len(rowCells) > columns {
+			columns = len(rowCells)
+		}
+		cells = append(cells, rowCells)
+	}
+
+	var out []string
+	for i, rowCells := range cells {
+		for len(rowCells) < columns {
+			rowCells = append(rowCells, "")
+		}
+		out = append(out, "| "+strings.Join(rowCells, " | ")+" |")
+		if i == 0 {
+			out = append(out, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return out
+}
+
+// dedent removes the indentation shared by all non-blank lines
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent <= 0 {
+		return lines
+	}
+
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent {
+			out[i] = line[indent:]
+		} else {
+			out[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return out
+}
@@ -0,0 +1,220 @@
+package convert
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// Markdown patterns
+var (
+	mdHeadingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdCheckboxPattern = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	mdFencePattern    = regexp.MustCompile("^\\s*(```|~~~)\\s*([^\\s`]*)")
+	mdTableRule       = regexp.MustCompile(`^\|(\s*:?-+:?\s*\|)+\s*$`)
+	mdBulletPattern   = regexp.MustCompile(`^(\s*)[*+]\s+`)
+)
+
+// mdInlinePattern matches Markdown inline markup: code, links, bold, strike-through and
+// italics. Each alternative is a submatch group pair in mdInlineMarkup, tried leftmost first,
+// so that ** is read as bold before * is read as italics.
+var mdInlinePattern = regexp.MustCompile("`([^`]+)`" +
+	`|\[([^\]]+)\]\(([^)\s]+)\)` +
+	`|\*\*([^*]+)\*\*|__([^_]+)__` +
+	`|~~([^~]+)~~` +
+	`|\*([^*\s](?:[^*]*[^*\s])?)\*|_([^_\s](?:[^_]*[^_\s])?)_`)
+
+// mdInlineMarkup gives the org markup for the text of each group of mdInlinePattern
+var mdInlineMarkup = []struct{ open, close string }{
+	1: {"=", "="},
+	4: {"*", "*"}, 5: {"*", "*"},
+	6: {"+", "+"},
+	7: {"/", "/"}, 8: {"/", "/"},
+}
+
+// ImportMarkdown adds the headings and task lists of a Markdown file to an org file.
+// Headings become org headings, checkboxes become TODO or done headings below them, fenced
+// code blocks become source blocks and everything else becomes notes. Content before the first
+// heading that isn't a task goes under a heading named after the source. Returns the number
+// of headings added.
+func ImportMarkdown(r io.Reader, orgFile *model.OrgFile, cfg *config.Config, name string) (int, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	// The shallowest Markdown heading becomes a top-level org heading
+	minLevel := 0
+	inFence := false
+	for _, line := range lines {
+		if mdFencePattern.MatchString(line) {
+			inFence = !inFence
+		} else if matches := mdHeadingPattern.FindStringSubmatch(line); matches != nil && !inFence {
+			if minLevel == 0 || len(matches[1]) < minLevel {
+				minLevel = len(matches[1])
+			}
+		}
+	}
+
+	todoState := openState(cfg)
+	doneState := model.TodoState(cfg.GetDoneState())
+	now := cfg.Now()
+
+	var added []*model.Item
+	var stack []*model.Item // Open headings, innermost last
+	var tasks []int         // Indentation of the tasks on the stack above the last heading
+	var current *model.Item // Item that notes go to
+	var heading *model.Item // Last heading, which notes go to after a task list ends
+
+	addItem := func(item *model.Item) {
+		for len(stack) > 0 && stack[len(stack)-1].Level >= item.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			orgFile.Items = append(orgFile.Items, item)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, item)
+		}
+		stack = append(stack, item)
+		current = item
+		added = append(added, item)
+	}
+	addNote := func(note string) {
+		if current == nil {
+			addItem(&model.Item{Level: 1, Title: name})
+		}
+		// Skip leading and repeated blank lines
+		if strings.TrimSpace(note) == "" && (len(current.Notes) == 0 || strings.TrimSpace(current.Notes[len(current.Notes)-1]) == "") {
+			return
+		}
+		current.Notes = append(current.Notes, note)
+	}
+
+	headingLevel := 0
+	inFence = false
+	for _, line := range lines {
+		if matches := mdFencePattern.FindStringSubmatch(line); matches != nil {
+			if inFence {
+				addNote("#+END_SRC")
+			} else {
+				addNote(strings.TrimSpace("#+BEGIN_SRC " + matches[2]))
+			}
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			addNote(line)
+			continue
+		}
+
+		if matches := mdHeadingPattern.FindStringSubmatch(line); matches != nil {
+			headingLevel = len(matches[1]) - minLevel + 1
+			tasks = nil
+			heading = &model.Item{Level: headingLevel, Title: convertMarkdownInline(matches[2])}
+			addItem(heading)
+			continue
+		}
+
+		if matches := mdCheckboxPattern.FindStringSubmatch(line); matches != nil {
+			indent := len(strings.ReplaceAll(matches[1], "\t", "    "))
+			for len(tasks) > 0 && tasks[len(tasks)-1] >= indent {
+				tasks = tasks[:len(tasks)-1]
+			}
+			tasks = append(tasks, indent)
+
+			item := &model.Item{Level: headingLevel + len(tasks), State: todoState, Title: convertMarkdownInline(matches[3])}
+			if matches[2] != " " {
+				// Markdown doesn't say when a task was done, so it is closed when imported
+				item.State = doneState
+				item.Closed = &now
+			}
+			addItem(item)
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case mdTableRule.MatchString(trimmed):
+			// Org tables use + where columns meet
+			cells := strings.Split(strings.Trim(trimmed, "|"), "|")
+			for i := range cells {
+				cells[i] = strings.Repeat("-", len(cells[i]))
+			}
+			addNote("|" + strings.Join(cells, "+") + "|")
+		case trimmed == "":
+			addNote("")
+		default:
+			if len(tasks) > 0 {
+				if line == trimmed {
+					// Unindented text ends the task list
+					tasks = nil
+					current = heading
+				} else {
+					// Indented text belongs to the task above it
+					line = trimmed
+				}
+			}
+			// Org reads lines starting with * as headings
+			line = mdBulletPattern.ReplaceAllString(line, "$1- ")
+			addNote(convertMarkdownInline(line))
+		}
+	}
+
+	// Drop trailing blank notes
+	for _, item := range added {
+		for len(item.Notes) > 0 && strings.TrimSpace(item.Notes[len(item.Notes)-1]) == "" {
+			item.Notes = item.Notes[:len(item.Notes)-1]
+		}
+	}
+
+	return len(added), nil
+}
+
+// convertMarkdownInline converts Markdown links, code, bold and strikethrough to org markup
+func convertMarkdownInline(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, match := range mdInlinePattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[0], match[1]
+		b.WriteString(text[last:start])
+		last = end
+
+		group := 1
+		for match[2*group] < 0 {
+			group++
+		}
+		content := text[match[2*group]:match[2*group+1]]
+		switch {
+		case group == 2:
+			b.WriteString("[[" + text[match[6]:match[7]] + "][" + content + "]]")
+		case text[start] == '_' && (isWordByte(text, start-1) || isWordByte(text, end)):
+			// Underscores inside words, as in snake_case, aren't emphasis
+			b.WriteString(text[start:end])
+		default:
+			markup := mdInlineMarkup[group]
+			b.WriteString(markup.open + content + markup.close)
+		}
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// isWordByte reports whether the byte at i is a letter, digit or underscore, false if i is
+// outside of s
+func isWordByte(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
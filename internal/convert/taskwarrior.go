@@ -0,0 +1,388 @@
+package convert
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// taskwarriorDateFormat is the UTC date format of `task export`
+const taskwarriorDateFormat = "20060102T150405Z"
+
+// Taskwarrior priorities and their org equivalents
+var (
+	taskwarriorPriorities  = map[string]model.Priority{"H": model.PriorityA, "M": model.PriorityB, "L": model.PriorityC}
+	orgTaskwarriorPriority = map[model.Priority]string{model.PriorityA: "H", model.PriorityB: "M", model.PriorityC: "L"}
+)
+
+var (
+	// annotationPattern matches a note written for a Taskwarrior annotation: - [2025-01-06 Mon 10:00] text
+	annotationPattern = regexp.MustCompile(`^\s*- \[(\d{4}-\d{2}-\d{2} \w{3} \d{2}:\d{2})\] (.*)$`)
+	uuidPattern       = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// taskwarriorTask is a task as read and written by `task export` and `task import`
+type taskwarriorTask struct {
+	UUID        string                  `json:"uuid"`
+	Description string                  `json:"description"`
+	Status      string                  `json:"status"`
+	Entry       string                  `json:"entry,omitempty"`
+	Priority    string                  `json:"priority,omitempty"`
+	Project     string                  `json:"project,omitempty"`
+	Tags        []string                `json:"tags,omitempty"`
+	Due         string                  `json:"due,omitempty"`
+	Scheduled   string                  `json:"scheduled,omitempty"`
+	End         string                  `json:"end,omitempty"`
+	Depends     taskwarriorDepends      `json:"depends,omitempty"`
+	Annotations []taskwarriorAnnotation `json:"annotations,omitempty"`
+}
+
+// taskwarriorAnnotation is a timestamped note on a task
+type taskwarriorAnnotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// taskwarriorDepends holds the UUIDs a task depends on. Taskwarrior 2.6 and later write them
+// as an array, older versions as a comma-separated string.
+type taskwarriorDepends []string
+
+func (d *taskwarriorDepends) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*d = list
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*d = nil
+	for _, uuid := range strings.Split(value, ",") {
+		if uuid = strings.TrimSpace(uuid); uuid != "" {
+			*d = append(*d, uuid)
+		}
+	}
+	return nil
+}
+
+// ExportTaskwarrior writes every item with a TODO state as a JSON array that `task import`
+// accepts. The headings above an item become its project, and the :ID: property its UUID.
+// Items without a UUID get one derived from the file and outline path, so they keep
+// the same UUID between exports.
+func ExportTaskwarrior(w io.Writer, orgFile *model.OrgFile, cfg *config.Config) error {
+	tasks := []taskwarriorTask{}
+	walkTaskwarrior(orgFile.Items, nil, func(item *model.Item, path []string) {
+		if item.State != model.StateNone {
+			tasks = append(tasks, itemToTaskwarrior(orgFile, item, path, cfg))
+		}
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(tasks)
+}
+
+// walkTaskwarrior calls fn for every item with its outline path, including its own title
+func walkTaskwarrior(items []*model.Item, path []string, fn func(item *model.Item, path []string)) {
+	for _, item := range items {
+		itemPath := append(append([]string{}, path...), item.Title)
+		fn(item, itemPath)
+		walkTaskwarrior(item.Children, itemPath, fn)
+	}
+}
+
+// itemToTaskwarrior converts a single item; path is its outline path including its own title
+func itemToTaskwarrior(orgFile *model.OrgFile, item *model.Item, path []string, cfg *config.Config) taskwarriorTask {
+	task := taskwarriorTask{
+		UUID:        taskwarriorUUID(orgFile, item, path),
+		Description: item.Title,
+		Status:      "pending",
+		Priority:    orgTaskwarriorPriority[item.Priority],
+		Project:     taskwarriorProject(path[:len(path)-1]),
+		Tags:        item.Tags,
+	}
+	if cfg.IsDoneState(string(item.State)) {
+		task.Status = "completed"
+		if item.Closed != nil {
+			task.End = taskwarriorDate(*item.Closed)
+		}
+	}
+
+	if created := strings.Trim(item.GetProperty("CREATED"), "[]<>"); created != "" {
+		if t, err := time.ParseInLocation("2006-01-02 Mon 15:04", created, cfg.Zone()); err == nil {
+			task.Entry = taskwarriorDate(t)
+		}
+	}
+	if item.Deadline != nil {
+		task.Due = taskwarriorDate(*item.Deadline)
+	}
+	if item.Scheduled != nil {
+		task.Scheduled = taskwarriorDate(*item.Scheduled)
+	}
+
+	for _, id := range item.BlockerIDs() {
+		if blocker := orgFile.FindByID(id); blocker != nil {
+			task.Depends = append(task.Depends, taskwarriorUUID(orgFile, blocker, nil))
+		}
+	}
+
+	for _, note := range item.Notes {
+		if matches := annotationPattern.FindStringSubmatch(note); matches != nil {
+			if t, err := time.ParseInLocation("2006-01-02 Mon 15:04", matches[1], cfg.Zone()); err == nil {
+				task.Annotations = append(task.Annotations, taskwarriorAnnotation{
+					Entry:       taskwarriorDate(t),
+					Description: matches[2],
+				})
+			}
+		}
+	}
+
+	return task
+}
+
+// ImportTaskwarrior adds the tasks of a `task export` file to an org file. Each task is placed
+// below the headings named by its project, which are created if needed. Tasks whose UUID is
+// already used by an item, as its :ID: or the UUID ExportTaskwarrior gives it, are skipped,
+// as are deleted tasks. Returns the number of tasks added and skipped.
+func ImportTaskwarrior(r io.Reader, orgFile *model.OrgFile, cfg *config.Config) (added, skipped int, err error) {
+	tasks, err := readTaskwarrior(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	known := make(map[string]bool)
+	walkTaskwarrior(orgFile.Items, nil, func(item *model.Item, path []string) {
+		known[taskwarriorUUID(orgFile, item, path)] = true
+	})
+
+	for _, task := range tasks {
+		uuid := strings.ToLower(task.UUID)
+		if task.Status == "deleted" || (uuid != "" && known[uuid]) {
+			skipped++
+			continue
+		}
+		if uuid != "" {
+			known[uuid] = true
+		}
+
+		var parent *model.Item
+		if task.Project != "" {
+			parent = projectHeading(orgFile, strings.Split(task.Project, "."))
+		}
+
+		item := taskwarriorToItem(task, cfg)
+		if parent == nil {
+			item.Level = 1
+			orgFile.Items = append(orgFile.Items, item)
+		} else {
+			item.Level = parent.Level + 1
+			parent.Children = append(parent.Children, item)
+		}
+		added++
+	}
+
+	return added, skipped, nil
+}
+
+// readTaskwarrior reads a JSON array of tasks, or one task per line as older versions export
+func readTaskwarrior(r io.Reader) ([]taskwarriorTask, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []taskwarriorTask
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &tasks); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return tasks, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	for decoder.More() {
+		var task taskwarriorTask
+		if err := decoder.Decode(&task); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// taskwarriorToItem converts a task to a heading without placing it in the outline
+func taskwarriorToItem(task taskwarriorTask, cfg *config.Config) *model.Item {
+	item := &model.Item{
+		Title:      strings.Join(strings.Fields(task.Description), " "),
+		State:      openState(cfg),
+		Priority:   taskwarriorPriorities[task.Priority],
+		Properties: make(map[string]string),
+	}
+	if item.Title == "" {
+		item.Title = "(no description)"
+	}
+
+	for _, tag := range task.Tags {
+		if tag := invalidTagChars.ReplaceAllString(tag, "_"); strings.Trim(tag, "_") != "" {
+			item.Tags = append(item.Tags, tag)
+		}
+	}
+
+	// Planning info all goes on one line right below the heading
+	var planning []string
+	if task.Status == "completed" {
+		item.State = model.TodoState(cfg.GetDoneState())
+		if end, ok := parseTaskwarriorDate(task.End, cfg.Zone()); ok {
+			item.Closed = &end
+			planning = append(planning, "CLOSED: ["+parser.FormatOrgDateTime(end)+"]")
+		}
+	}
+	if scheduled, ok := parseTaskwarriorDate(task.Scheduled, cfg.Zone()); ok {
+		// Taskwarrior dates always have a time, so only those at midnight are taken as whole days
+		item.Scheduled = &scheduled
+		item.ScheduledHasTime = hasTimeOfDay(scheduled)
+		planning = append(planning, "SCHEDULED: "+parser.FormatTimestampRange(scheduled, scheduled, item.ScheduledHasTime))
+	}
+	if due, ok := parseTaskwarriorDate(task.Due, cfg.Zone()); ok {
+		item.Deadline = &due
+		item.DeadlineHasTime = hasTimeOfDay(due)
+		planning = append(planning, "DEADLINE: "+parser.FormatTimestampRange(due, due, item.DeadlineHasTime))
+	}
+	if len(planning) > 0 {
+		item.Notes = append(item.Notes, strings.Join(planning, " "))
+	}
+
+	if task.UUID != "" {
+		item.Properties["ID"] = task.UUID
+	}
+	if entry, ok := parseTaskwarriorDate(task.Entry, cfg.Zone()); ok {
+		item.Properties["CREATED"] = "[" + parser.FormatOrgDateTime(entry) + "]"
+	}
+	if len(task.Depends) > 0 {
+		item.Properties["BLOCKER"] = strings.Join(task.Depends, " ")
+	}
+	if len(item.Properties) > 0 {
+		item.Notes = append(item.Notes, ":PROPERTIES:")
+		for _, name := range []string{"ID", "CREATED", "BLOCKER"} {
+			if value, ok := item.Properties[name]; ok {
+				item.Notes = append(item.Notes, ":"+name+": "+value)
+			}
+		}
+		item.Notes = append(item.Notes, ":END:")
+	}
+
+	for _, annotation := range task.Annotations {
+		text := strings.Join(strings.Fields(annotation.Description), " ")
+		if entry, ok := parseTaskwarriorDate(annotation.Entry, cfg.Zone()); ok {
+			item.Notes = append(item.Notes, "- ["+parser.FormatOrgDateTime(entry)+"] "+text)
+		} else {
+			item.Notes = append(item.Notes, "- "+text)
+		}
+	}
+
+	return item
+}
+
+// taskwarriorProject joins the headings above an item into a project. Taskwarrior separates
+// the levels of a project with dots, so the dots in the headings become underscores.
+func taskwarriorProject(path []string) string {
+	names := make([]string, len(path))
+	for i, title := range path {
+		names[i] = strings.ReplaceAll(title, ".", "_")
+	}
+	return strings.Join(names, ".")
+}
+
+// projectHeading finds the heading at the given outline path, creating missing headings.
+// Headings with dots match the names taskwarriorProject gives them.
+func projectHeading(orgFile *model.OrgFile, path []string) *model.Item {
+	var parent *model.Item
+	items := &orgFile.Items
+	for _, title := range path {
+		title = strings.TrimSpace(title)
+		if title == "" {
+			continue
+		}
+
+		var found *model.Item
+		for _, item := range *items {
+			if item.Title == title || strings.ReplaceAll(item.Title, ".", "_") == title {
+				found = item
+				break
+			}
+		}
+		if found == nil {
+			found = &model.Item{Level: 1, Title: title}
+			if parent != nil {
+				found.Level = parent.Level + 1
+			}
+			*items = append(*items, found)
+		}
+
+		parent = found
+		items = &found.Children
+	}
+	return parent
+}
+
+// taskwarriorUUID returns the item's :ID: if it is a UUID, or derives a stable one from
+// its ID or, failing that, its file and outline path
+func taskwarriorUUID(orgFile *model.OrgFile, item *model.Item, path []string) string {
+	id := item.GetProperty("ID")
+	if uuidPattern.MatchString(id) {
+		return strings.ToLower(id)
+	}
+
+	key := id
+	if key == "" {
+		file := item.SourceFile
+		if file == "" {
+			file = orgFile.Path
+		}
+		key = filepath.Base(file) + "\x00" + strings.Join(path, "\x00")
+	}
+
+	// Name-based UUID in the version 5 layout
+	sum := sha1.Sum([]byte(key))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(sum[:16])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// taskwarriorDate converts an org date to Taskwarrior's UTC format
+func taskwarriorDate(t time.Time) string {
+	return t.UTC().Format(taskwarriorDateFormat)
+}
+
+// parseTaskwarriorDate parses a Taskwarrior date into the time in loc
+func parseTaskwarriorDate(value string, loc *time.Location) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(taskwarriorDateFormat, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.In(loc), true
+}
+
+// hasTimeOfDay returns true if the time is not at midnight, for dates from formats that
+// don't say whether they have a time
+func hasTimeOfDay(t time.Time) bool {
+	return t.Hour() != 0 || t.Minute() != 0
+}
@@ -0,0 +1,59 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// parseOrg parses content as an org file with the given name in a temporary directory
+func parseOrg(t *testing.T, content, name string, cfg *config.Config) *model.OrgFile {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	orgFile, err := parser.ParseOrgFile(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return orgFile
+}
+
+func TestTaskwarriorProjectWithDots(t *testing.T) {
+	cfg := config.DefaultConfig()
+	orgFile := parseOrg(t, "* Work\n** v1.2 release\n*** TODO Tag the build\n", "work.org", cfg)
+
+	var exported bytes.Buffer
+	if err := ExportTaskwarrior(&exported, orgFile, cfg); err != nil {
+		t.Fatal(err)
+	}
+	var tasks []taskwarriorTask
+	if err := json.Unmarshal(exported.Bytes(), &tasks); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Project != "Work.v1_2 release" {
+		t.Fatalf("exported tasks = %+v; want one in project %q", tasks, "Work.v1_2 release")
+	}
+
+	// Importing into another file with the same headings puts the task back below them
+	target := parseOrg(t, "* Work\n** v1.2 release\n", "other.org", cfg)
+	if added, _, err := ImportTaskwarrior(bytes.NewReader(exported.Bytes()), target, cfg); err != nil || added != 1 {
+		t.Fatalf("ImportTaskwarrior = %d added, %v; want 1 added", added, err)
+	}
+	var titles []string
+	for _, item := range target.AllItems() {
+		titles = append(titles, strings.Repeat("*", item.Level)+" "+item.Title)
+	}
+	want := []string{"* Work", "** v1.2 release", "*** Tag the build"}
+	if strings.Join(titles, "\n") != strings.Join(want, "\n") {
+		t.Errorf("imported outline = %q; want %q", titles, want)
+	}
+}
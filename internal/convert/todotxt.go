@@ -0,0 +1,296 @@
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// todoTxtDateFormat is the date format used throughout todo.txt
+const todoTxtDateFormat = "2006-01-02"
+
+// todoTxtPriorityPattern matches a priority such as (A) at the start of a task
+var todoTxtPriorityPattern = regexp.MustCompile(`^\(([A-Z])\)$`)
+
+// todoTxtTask is a single line of a todo.txt file
+type todoTxtTask struct {
+	Done      bool
+	Completed *time.Time
+	Priority  model.Priority
+	Title     string
+	Tags      []string // Projects without the +, contexts with their @
+	Due       *time.Time
+	Threshold *time.Time
+	ID        string
+}
+
+// ExportTodoTxt writes every item with a TODO state as a todo.txt line. Tags become +projects,
+// or @contexts if they start with @, the deadline becomes due: and the scheduled date t:.
+// Items with an :ID: property carry it as id: so that a later sync can find them again.
+func ExportTodoTxt(w io.Writer, orgFile *model.OrgFile, cfg *config.Config) error {
+	bw := bufio.NewWriter(w)
+	for _, item := range orgFile.AllItems() {
+		if item.State == model.StateNone {
+			continue
+		}
+
+		var parts []string
+		done := cfg.IsDoneState(string(item.State))
+		if done {
+			parts = append(parts, "x")
+			if item.Closed != nil {
+				parts = append(parts, item.Closed.Format(todoTxtDateFormat))
+			}
+		} else if item.Priority != model.PriorityNone {
+			parts = append(parts, "("+string(item.Priority)+")")
+		}
+
+		parts = append(parts, strings.Fields(item.Title)...)
+		for _, tag := range item.Tags {
+			if strings.HasPrefix(tag, "@") {
+				parts = append(parts, tag)
+			} else {
+				parts = append(parts, "+"+tag)
+			}
+		}
+		if item.Deadline != nil {
+			parts = append(parts, "due:"+item.Deadline.Format(todoTxtDateFormat))
+		}
+		if item.Scheduled != nil {
+			parts = append(parts, "t:"+item.Scheduled.Format(todoTxtDateFormat))
+		}
+		if id := item.GetProperty("ID"); id != "" {
+			parts = append(parts, "id:"+id)
+		}
+		// Completed tasks lose their (A), so keep the priority as a tag
+		if done && item.Priority != model.PriorityNone {
+			parts = append(parts, "pri:"+string(item.Priority))
+		}
+
+		fmt.Fprintln(bw, strings.Join(parts, " "))
+	}
+	return bw.Flush()
+}
+
+// ImportTodoTxt adds the tasks of a todo.txt file to an org file as top-level headings.
+// With sync set, tasks that match an existing item by id: or by title update that item's
+// state, priority, tags and dates instead of being added again. Returns the number of
+// items added and changed by the update.
+func ImportTodoTxt(r io.Reader, orgFile *model.OrgFile, cfg *config.Config, sync bool) (added, updated int, err error) {
+	var tasks []todoTxtTask
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if task, ok := parseTodoTxtLine(scanner.Text(), cfg.Zone()); ok {
+			tasks = append(tasks, task)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	byID := make(map[string]*model.Item)
+	byTitle := make(map[string]*model.Item)
+	if sync {
+		for _, item := range orgFile.AllItems() {
+			if item.State == model.StateNone {
+				continue
+			}
+			if id := item.GetProperty("ID"); id != "" {
+				byID[id] = item
+			}
+			title := strings.ToLower(strings.Join(strings.Fields(item.Title), " "))
+			if _, ok := byTitle[title]; !ok {
+				byTitle[title] = item
+			}
+		}
+	}
+
+	for _, task := range tasks {
+		item := byID[task.ID]
+		if task.ID == "" || item == nil {
+			item = byTitle[strings.ToLower(task.Title)]
+		}
+
+		if item != nil {
+			if updateFromTodoTxt(item, task, cfg) {
+				updated++
+			}
+			continue
+		}
+
+		item = &model.Item{Level: 1, Title: task.Title}
+		updateFromTodoTxt(item, task, cfg)
+		orgFile.Items = append(orgFile.Items, item)
+		added++
+	}
+
+	return added, updated, nil
+}
+
+// updateFromTodoTxt copies a task's state, priority and dates onto an item, and adds its
+// tags to the item's. The item keeps tags the task doesn't have, as todo.txt files that
+// were written elsewhere don't carry them. Returns true if the item changed.
+func updateFromTodoTxt(item *model.Item, task todoTxtTask, cfg *config.Config) bool {
+	changed := false
+	wasDone := cfg.IsDoneState(string(item.State))
+	switch {
+	case task.Done && !wasDone:
+		item.State = model.TodoState(cfg.GetDoneState())
+		closed := cfg.Now()
+		if task.Completed != nil {
+			closed = *task.Completed
+		}
+		item.Closed = &closed
+		changed = true
+	case !task.Done && (wasDone || item.State == model.StateNone):
+		// Keep in-progress states such as PROG, only reopen finished items
+		item.State = openState(cfg)
+		item.Closed = nil
+		changed = true
+	}
+
+	if item.Priority != task.Priority {
+		item.Priority = task.Priority
+		changed = true
+	}
+	for _, tag := range task.Tags {
+		if !hasTag(item.Tags, tag) {
+			item.Tags = append(item.Tags, tag)
+			changed = true
+		}
+	}
+	if task.ID != "" && item.GetProperty("ID") == "" {
+		item.SetProperty("ID", task.ID)
+		changed = true
+	}
+
+	if !sameDay(item.Deadline, task.Due) {
+		item.Deadline = task.Due
+		item.DeadlineEnd = nil
+		item.DeadlineHasTime = false
+		if task.Due == nil {
+			item.DeadlineRepeater = ""
+			item.DeadlineWarning = ""
+		}
+		changed = true
+	}
+	if !sameDay(item.Scheduled, task.Threshold) {
+		item.Scheduled = task.Threshold
+		item.ScheduledEnd = nil
+		item.ScheduledHasTime = false
+		if task.Threshold == nil {
+			item.ScheduledRepeater = ""
+			item.ScheduledDelay = ""
+		}
+		changed = true
+	}
+	parser.UpdatePlanning(item)
+	return changed
+}
+
+// hasTag reports whether tags holds tag
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// sameDay reports whether two optional dates fall on the same day, so that times of day
+// set in org aren't lost to todo.txt's date-only format
+func sameDay(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Format(todoTxtDateFormat) == b.Format(todoTxtDateFormat)
+}
+
+// parseTodoTxtLine parses a todo.txt line:
+// [x [completion date]] [(A)] [creation date] description +project @context key:value
+func parseTodoTxtLine(line string, loc *time.Location) (todoTxtTask, bool) {
+	var task todoTxtTask
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return task, false
+	}
+
+	if fields[0] == "x" {
+		task.Done = true
+		fields = fields[1:]
+		if len(fields) > 0 {
+			if t, err := time.ParseInLocation(todoTxtDateFormat, fields[0], loc); err == nil {
+				task.Completed = &t
+				fields = fields[1:]
+			}
+		}
+	}
+	if len(fields) > 0 {
+		if matches := todoTxtPriorityPattern.FindStringSubmatch(fields[0]); matches != nil {
+			task.Priority = todoTxtPriority(matches[1])
+			fields = fields[1:]
+		}
+	}
+	// The creation date has no place in org
+	if len(fields) > 0 {
+		if _, err := time.ParseInLocation(todoTxtDateFormat, fields[0], loc); err == nil {
+			fields = fields[1:]
+		}
+	}
+
+	var words []string
+	for _, field := range fields {
+		key, value, hasValue := strings.Cut(field, ":")
+		switch {
+		case len(field) > 1 && field[0] == '+':
+			if tag := invalidTagChars.ReplaceAllString(field[1:], "_"); strings.Trim(tag, "_") != "" {
+				task.Tags = append(task.Tags, tag)
+			}
+		case len(field) > 1 && field[0] == '@':
+			if tag := invalidTagChars.ReplaceAllString(field, "_"); strings.Trim(tag, "_@") != "" {
+				task.Tags = append(task.Tags, tag)
+			}
+		case hasValue && key == "due":
+			if t, err := time.ParseInLocation(todoTxtDateFormat, value, loc); err == nil {
+				task.Due = &t
+			} else {
+				words = append(words, field)
+			}
+		case hasValue && key == "t":
+			if t, err := time.ParseInLocation(todoTxtDateFormat, value, loc); err == nil {
+				task.Threshold = &t
+			} else {
+				words = append(words, field)
+			}
+		case hasValue && key == "id" && value != "":
+			task.ID = value
+		case hasValue && key == "pri" && len(value) == 1:
+			task.Priority = todoTxtPriority(strings.ToUpper(value))
+		default:
+			words = append(words, field)
+		}
+	}
+	task.Title = strings.Join(words, " ")
+
+	return task, task.Title != ""
+}
+
+// todoTxtPriority maps todo.txt's A-Z priorities onto org's A-C
+func todoTxtPriority(letter string) model.Priority {
+	switch letter {
+	case "A":
+		return model.PriorityA
+	case "B":
+		return model.PriorityB
+	default:
+		return model.PriorityC
+	}
+}
@@ -0,0 +1,219 @@
+package gitsync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// ConflictError reports files that git could not merge
+type ConflictError struct {
+	Files []string
+}
+
+func (e *ConflictError) Error() string {
+	return "git conflict in " + strings.Join(e.Files, ", ")
+}
+
+// Repo is a git repository holding org files. All work is done by the local git binary.
+type Repo struct {
+	root string
+}
+
+// Find returns the repository that path (a file or directory) is in, or nil if it isn't
+// in one or git isn't installed
+func Find(path string) *Repo {
+	dir := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	out, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil
+	}
+	return &Repo{root: strings.TrimSpace(out)}
+}
+
+// Pull rebases the current branch onto its upstream. If the rebase conflicts it is
+// aborted, leaving the files as they were, and a *ConflictError lists the conflicting
+// files. Files that are already in conflict are reported the same way without pulling.
+func (r *Repo) Pull() error {
+	if files := r.unmerged(); len(files) > 0 {
+		return &ConflictError{Files: files}
+	}
+	if _, err := r.git("rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		// Nothing to pull from
+		return nil
+	}
+
+	if _, err := r.git("pull", "--rebase", "--quiet"); err != nil {
+		if files := r.unmerged(); len(files) > 0 {
+			r.git("rebase", "--abort")
+			return &ConflictError{Files: files}
+		}
+		return err
+	}
+	return nil
+}
+
+// Commit stages the files and commits them with message, leaving anything else that is
+// staged alone. It reports whether a commit was made: nothing is committed if none of
+// the files changed. Files in conflict are never committed; a *ConflictError is
+// returned instead.
+func (r *Repo) Commit(files []string, message string) (bool, error) {
+	paths, err := r.relative(files)
+	if err != nil {
+		return false, err
+	}
+
+	var conflicts []string
+	for _, file := range r.unmerged() {
+		for _, path := range paths {
+			if file == path {
+				conflicts = append(conflicts, file)
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return false, &ConflictError{Files: conflicts}
+	}
+
+	if _, err := r.git(append([]string{"add", "--"}, paths...)...); err != nil {
+		return false, err
+	}
+	if _, err := r.git(append([]string{"diff", "--cached", "--quiet", "--"}, paths...)...); err == nil {
+		return false, nil
+	}
+	if _, err := r.git(append([]string{"commit", "--quiet", "-m", message, "--"}, paths...)...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// headVersion returns the contents of a file in the last commit, or nil if it isn't in it
+func (r *Repo) headVersion(file string) []byte {
+	paths, err := r.relative([]string{file})
+	if err != nil {
+		return nil
+	}
+	out, err := r.git("show", "HEAD:"+paths[0])
+	if err != nil {
+		return nil
+	}
+	return []byte(out)
+}
+
+// unmerged returns the files with unresolved conflicts, relative to the repository root
+func (r *Repo) unmerged() []string {
+	out, err := r.git("diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files
+}
+
+// relative converts paths to be relative to the repository root, as git prints them
+func (r *Repo) relative(files []string) ([]string, error) {
+	root, err := filepath.EvalSymlinks(r.root)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		// The root git prints has symlinks resolved, so the file's directory must too
+		if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+			abs = filepath.Join(dir, filepath.Base(abs))
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("%s is not in the git repository at %s", file, r.root)
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	return paths, nil
+}
+
+// git runs a git command in the repository
+func (r *Repo) git(args ...string) (string, error) {
+	return git(r.root, args...)
+}
+
+// git runs a git command in dir, returning its output. The error includes the first
+// line git wrote to stderr.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if line, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); line != "" {
+				return "", fmt.Errorf("git %s: %s", args[0], line)
+			}
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// Files returns the files an org file is saved to: the file itself, or in multi-file
+// mode the file behind each top-level item
+func Files(orgFile *model.OrgFile) []string {
+	if len(orgFile.Items) == 0 || orgFile.Items[0].SourceFile == "" {
+		return []string{orgFile.Path}
+	}
+	var files []string
+	for _, item := range orgFile.Items {
+		if item.SourceFile != "" {
+			files = append(files, item.SourceFile)
+		}
+	}
+	return files
+}
+
+// CommitSaved commits the files of an org file that was just saved, when auto-commit is
+// enabled and they are in a git repository. The message summarizes the changes since the
+// last commit.
+func CommitSaved(orgFile *model.OrgFile, cfg *config.Config) error {
+	if !cfg.Git.AutoCommit {
+		return nil
+	}
+	repo := Find(orgFile.Path)
+	if repo == nil {
+		return nil
+	}
+	files := Files(orgFile)
+	_, err := repo.Commit(files, repo.message(files, cfg))
+	return err
+}
+
+// Pull pulls the repository the org file at path is in, when pulling is enabled
+func Pull(path string, cfg *config.Config) error {
+	if !cfg.Git.Pull {
+		return nil
+	}
+	repo := Find(path)
+	if repo == nil {
+		return nil
+	}
+	return repo.Pull()
+}
@@ -0,0 +1,160 @@
+package gitsync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// message describes the changes to files since the last commit. The subject counts the
+// items added and removed, the state changes and the time clocked, and the body lists them.
+func (r *Repo) message(files []string, cfg *config.Config) string {
+	before := make(map[string]*model.Item)
+	after := make(map[string]*model.Item)
+	var beforeOrder, order []string
+	names := make([]string, 0, len(files))
+
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+		if old, err := parser.Parse(bytes.NewReader(r.headVersion(file)), file, cfg); err == nil {
+			indexItems(old.Items, "", before, &beforeOrder)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if current, err := parser.Parse(bytes.NewReader(data), file, cfg); err == nil {
+			indexItems(current.Items, "", after, &order)
+		}
+	}
+
+	var summary, body []string
+	var added, removed, changed, clockIns int
+	var clocked time.Duration
+	for _, key := range order {
+		item := after[key]
+		old, existed := before[key]
+		if !existed {
+			added++
+			body = append(body, "Add: "+item.Title)
+		} else if old.State != item.State {
+			changed++
+			body = append(body, fmt.Sprintf("%s: %s -> %s", item.Title, stateName(old.State), stateName(item.State)))
+		}
+
+		spent := clockedTime(item)
+		if existed {
+			spent -= clockedTime(old)
+		}
+		if spent > 0 {
+			clocked += spent
+			body = append(body, fmt.Sprintf("Clock %s on %s", formatDuration(spent), item.Title))
+		} else if clockedIn(item) && (!existed || !clockedIn(old)) {
+			clockIns++
+			body = append(body, "Clock in on "+item.Title)
+		} else if existed && clockedIn(old) && !clockedIn(item) {
+			body = append(body, "Clock out on "+item.Title)
+		}
+	}
+	for _, key := range beforeOrder {
+		if _, ok := after[key]; !ok {
+			removed++
+			body = append(body, "Remove: "+before[key].Title)
+		}
+	}
+
+	if added > 0 {
+		summary = append(summary, fmt.Sprintf("%d added", added))
+	}
+	if removed > 0 {
+		summary = append(summary, fmt.Sprintf("%d removed", removed))
+	}
+	if changed == 1 {
+		summary = append(summary, "1 state change")
+	} else if changed > 1 {
+		summary = append(summary, fmt.Sprintf("%d state changes", changed))
+	}
+	if clocked > 0 {
+		summary = append(summary, formatDuration(clocked)+" clocked")
+	}
+	if clockIns > 0 {
+		summary = append(summary, "clocked in")
+	}
+
+	subject := "Update " + strings.Join(names, ", ")
+	if len(names) > 3 {
+		subject = fmt.Sprintf("Update %d org files", len(names))
+	}
+	if len(summary) > 0 {
+		subject += ": " + strings.Join(summary, ", ")
+	}
+	if len(body) == 0 {
+		return subject
+	}
+	return subject + "\n\n" + strings.Join(body, "\n")
+}
+
+// indexItems adds items and their descendants to index, keyed by their ID property or,
+// without one, by the titles of their ancestors and themselves. Keys are appended to order.
+func indexItems(items []*model.Item, prefix string, index map[string]*model.Item, order *[]string) {
+	for _, item := range items {
+		path := prefix + "/" + item.Title
+		key := path
+		if id := item.GetProperty("ID"); id != "" {
+			key = "id:" + id
+		}
+		// Tell apart items that share a title
+		base := key
+		for n := 2; index[key] != nil; n++ {
+			key = fmt.Sprintf("%s#%d", base, n)
+		}
+		index[key] = item
+		*order = append(*order, key)
+		indexItems(item.Children, path, index, order)
+	}
+}
+
+// clockedTime returns the time in an item's finished clock entries
+func clockedTime(item *model.Item) time.Duration {
+	var total time.Duration
+	for _, entry := range item.ClockEntries {
+		if entry.End != nil {
+			total += entry.End.Sub(entry.Start)
+		}
+	}
+	return total
+}
+
+// clockedIn reports whether an item has a running clock entry
+func clockedIn(item *model.Item) bool {
+	for _, entry := range item.ClockEntries {
+		if entry.End == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// stateName returns a state for display, showing a missing state as "none"
+func stateName(state model.TodoState) string {
+	if state == model.StateNone {
+		return "none"
+	}
+	return string(state)
+}
+
+// formatDuration formats a duration as hours and minutes, like 1h30m
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,136 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/convert"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// Events that hooks can be configured for
+const (
+	StateChanged = "state_changed"
+	Captured     = "captured"
+	ClockIn      = "clock_in"
+	ClockOut     = "clock_out"
+	DeadlineSet  = "deadline_set"
+	Deleted      = "deleted"
+	Saved        = "saved"
+	Reminder     = "reminder"
+	Pomodoro     = "pomodoro"
+)
+
+// Invocation is a hook command ready to run. The item is serialized when the invocation
+// is prepared, so later changes to it don't affect what the hook sees.
+type Invocation struct {
+	Event   string
+	Command string
+	Stdin   []byte
+	Env     []string
+	Timeout time.Duration
+}
+
+// Prepare builds the invocation of the hook for an event, or returns nil if no hook is
+// configured for it. item may be nil for events that aren't about an item, such as saved.
+// extra holds event details, such as the previous state, which are added to the JSON and
+// as ORG_<NAME> environment variables.
+func Prepare(cfg *config.Config, event string, item *model.Item, file string, extra map[string]string) *Invocation {
+	command := cfg.GetHook(event)
+	if strings.TrimSpace(command) == "" {
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"event": event,
+		"file":  file,
+	}
+	env := []string{"ORG_EVENT=" + event, "ORG_FILE=" + file}
+
+	if item != nil {
+		if data, err := convert.MarshalItem(item); err == nil {
+			payload["item"] = json.RawMessage(data)
+		}
+		env = append(env,
+			"ORG_TITLE="+item.Title,
+			"ORG_STATE="+string(item.State),
+			"ORG_PRIORITY="+string(item.Priority),
+			"ORG_TAGS="+strings.Join(item.Tags, ":"),
+			"ORG_ID="+item.GetProperty("ID"),
+			"ORG_SCHEDULED="+formatDate(item.Scheduled, item.ScheduledHasTime),
+			"ORG_DEADLINE="+formatDate(item.Deadline, item.DeadlineHasTime),
+		)
+	}
+	for name, value := range extra {
+		payload[name] = value
+		env = append(env, "ORG_"+strings.ToUpper(name)+"="+value)
+	}
+
+	stdin, _ := json.Marshal(payload)
+	return &Invocation{
+		Event:   event,
+		Command: command,
+		Stdin:   stdin,
+		Env:     env,
+		Timeout: time.Duration(cfg.Hooks.Timeout) * time.Second,
+	}
+}
+
+// Run runs the hook command through the shell, killing it if it runs past its timeout.
+// The error includes the first line the command wrote to stderr.
+func (inv *Invocation) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), inv.Timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", inv.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", inv.Command)
+	}
+	cmd.Stdin = bytes.NewReader(inv.Stdin)
+	cmd.Env = append(os.Environ(), inv.Env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// Don't wait for children of the shell that hold on to stderr after a timeout
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s hook timed out after %s", inv.Event, inv.Timeout)
+	}
+	if err != nil {
+		if line, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); line != "" {
+			return fmt.Errorf("%s hook failed: %v: %s", inv.Event, err, line)
+		}
+		return fmt.Errorf("%s hook failed: %v", inv.Event, err)
+	}
+	return nil
+}
+
+// Run runs the hook for an event right away, if one is configured
+func Run(cfg *config.Config, event string, item *model.Item, file string, extra map[string]string) error {
+	if inv := Prepare(cfg, event, item, file, extra); inv != nil {
+		return inv.Run()
+	}
+	return nil
+}
+
+// formatDate formats an optional date for the environment
+func formatDate(t *time.Time, hasTime bool) string {
+	if t == nil {
+		return ""
+	}
+	if hasTime {
+		return t.Format("2006-01-02 15:04")
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// DaysUntil returns the number of calendar days from the day of from to the day of to,
+// negative if to is on an earlier day. Only the dates count, not the times of day.
+func DaysUntil(from, to time.Time) int {
+	to = to.In(from.Location())
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// warningPattern matches a warning or delay cookie like -3d, or --3d for one that only
+// applies to the first occurrence of a repeating date
+var warningPattern = regexp.MustCompile(`^--?(\d+)([hdwmy])$`)
+
+// DeadlineWarningStart returns when the deadline starts showing up as approaching: the
+// period of its warning cookie before it, or defaultDays before it if it has none. The
+// item must have a deadline.
+func (item *Item) DeadlineWarningStart(defaultDays int) time.Time {
+	if matches := warningPattern.FindStringSubmatch(item.DeadlineWarning); matches != nil {
+		n, _ := strconv.Atoi(matches[1])
+		return addInterval(*item.Deadline, -n, matches[2])
+	}
+	return item.Deadline.AddDate(0, 0, -defaultDays)
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysUntil(t *testing.T) {
+	tests := []struct {
+		from, to time.Time
+		want     int
+	}{
+		{date(2025, 1, 6, 23, 59), date(2025, 1, 7, 0, 1), 1},
+		{date(2025, 1, 6, 0, 0), date(2025, 1, 6, 23, 59), 0},
+		{date(2025, 1, 6, 9, 0), date(2025, 1, 3, 18, 0), -3},
+		{date(2024, 12, 31, 12, 0), date(2025, 3, 1, 12, 0), 60},
+	}
+	for _, tt := range tests {
+		if got := DaysUntil(tt.from, tt.to); got != tt.want {
+			t.Errorf("DaysUntil(%v, %v) = %d; want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestDaysUntilAcrossDSTChange(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone data:", err)
+	}
+	// The day the clocks go forward is only 23 hours long
+	from := time.Date(2025, 3, 29, 12, 0, 0, 0, loc)
+	to := time.Date(2025, 3, 31, 0, 30, 0, 0, loc)
+	if got := DaysUntil(from, to); got != 2 {
+		t.Errorf("DaysUntil across a DST change = %d; want 2", got)
+	}
+}
+
+func TestDeadlineWarningStart(t *testing.T) {
+	deadline := date(2025, 1, 20, 0, 0)
+	tests := []struct {
+		warning     string
+		defaultDays int
+		want        time.Time
+	}{
+		{"", 14, date(2025, 1, 6, 0, 0)},
+		{"", 0, deadline},
+		{"-3d", 14, date(2025, 1, 17, 0, 0)},
+		{"--2w", 0, date(2025, 1, 6, 0, 0)},
+		{"-1m", 14, date(2024, 12, 20, 0, 0)},
+		{"-bogus", 7, date(2025, 1, 13, 0, 0)},
+	}
+	for _, tt := range tests {
+		item := &Item{Deadline: &deadline, DeadlineWarning: tt.warning}
+		if got := item.DeadlineWarningStart(tt.defaultDays); !got.Equal(tt.want) {
+			t.Errorf("DeadlineWarningStart(%d) with %q = %v; want %v", tt.defaultDays, tt.warning, got, tt.want)
+		}
+	}
+}
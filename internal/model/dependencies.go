@@ -0,0 +1,135 @@
+package model
+
+import "strings"
+
+// FindByID returns the item whose :ID: property matches id, or nil if none does
+func (of *OrgFile) FindByID(id string) *Item {
+	var found *Item
+	var search func([]*Item)
+	search = func(list []*Item) {
+		for _, item := range list {
+			if found != nil {
+				return
+			}
+			if item.GetProperty("ID") == id {
+				found = item
+				return
+			}
+			search(item.Children)
+		}
+	}
+	if id != "" {
+		search(of.Items)
+	}
+	return found
+}
+
+// FindParent returns the parent of target, or nil if target is a top-level item
+func (of *OrgFile) FindParent(target *Item) *Item {
+	var findInList func([]*Item) *Item
+	findInList = func(items []*Item) *Item {
+		for _, item := range items {
+			for _, child := range item.Children {
+				if child == target {
+					return item
+				}
+			}
+			if result := findInList(item.Children); result != nil {
+				return result
+			}
+		}
+		return nil
+	}
+	return findInList(of.Items)
+}
+
+// BlockerIDs returns the item IDs listed in the :BLOCKER: property
+// Accepts both plain space-separated IDs and the org-edna style ids(...) form
+func (item *Item) BlockerIDs() []string {
+	value := strings.TrimSpace(item.GetProperty("BLOCKER"))
+	if value == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(value, "ids(") && strings.HasSuffix(value, ")") {
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "ids("), ")")
+	}
+
+	var ids []string
+	for _, field := range strings.Fields(value) {
+		field = strings.TrimPrefix(field, "id:")
+		field = strings.Trim(field, "\"")
+		if field != "" {
+			ids = append(ids, field)
+		}
+	}
+	return ids
+}
+
+// IsOrdered returns true if the item's children must be completed in order (:ORDERED: t)
+func (item *Item) IsOrdered() bool {
+	value := strings.ToLower(strings.TrimSpace(item.GetProperty("ORDERED")))
+	return value != "" && value != "nil"
+}
+
+// OpenBlockers returns the items that must be completed before item can be marked done.
+// These are the items referenced by its :BLOCKER: property and, when its parent is
+// :ORDERED:, all earlier siblings. isDone reports whether a state counts as completed.
+func (of *OrgFile) OpenBlockers(item *Item, isDone func(TodoState) bool) []*Item {
+	return of.BlockerIndex().OpenBlockers(item, isDone)
+}
+
+// BlockerIndex finds the items blocking others without searching the whole tree for
+// each one. It holds the tree as it was when it was built.
+type BlockerIndex struct {
+	byID    map[string]*Item
+	parents map[*Item]*Item
+}
+
+// BlockerIndex indexes the items by ID and by parent
+func (of *OrgFile) BlockerIndex() *BlockerIndex {
+	idx := &BlockerIndex{byID: make(map[string]*Item), parents: make(map[*Item]*Item)}
+	var walk func(parent *Item, items []*Item)
+	walk = func(parent *Item, items []*Item) {
+		for _, item := range items {
+			// The first item with an ID wins, as with FindByID
+			if id := item.GetProperty("ID"); id != "" && idx.byID[id] == nil {
+				idx.byID[id] = item
+			}
+			if parent != nil {
+				idx.parents[item] = parent
+			}
+			walk(item, item.Children)
+		}
+	}
+	walk(nil, of.Items)
+	return idx
+}
+
+// OpenBlockers works like OrgFile.OpenBlockers
+func (idx *BlockerIndex) OpenBlockers(item *Item, isDone func(TodoState) bool) []*Item {
+	var blockers []*Item
+
+	for _, id := range item.BlockerIDs() {
+		blocker := idx.byID[id]
+		// References to unknown IDs are ignored rather than blocking forever
+		if blocker != nil && blocker != item && !isDone(blocker.State) {
+			blockers = append(blockers, blocker)
+		}
+	}
+
+	parent := idx.parents[item]
+	if parent != nil && parent.IsOrdered() {
+		for _, sibling := range parent.Children {
+			if sibling == item {
+				break
+			}
+			// Only actionable siblings can block
+			if sibling.State != StateNone && !isDone(sibling.State) {
+				blockers = append(blockers, sibling)
+			}
+		}
+	}
+
+	return blockers
+}
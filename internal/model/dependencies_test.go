@@ -0,0 +1,42 @@
+package model
+
+import "testing"
+
+func TestOpenBlockers(t *testing.T) {
+	design := &Item{Level: 2, State: "DONE", Title: "Design"}
+	build := &Item{Level: 2, State: "TODO", Title: "Build"}
+	ship := &Item{Level: 2, State: "TODO", Title: "Ship"}
+	release := &Item{Level: 1, Title: "Release", Properties: map[string]string{"ORDERED": "t"}, Children: []*Item{design, build, ship}}
+	docs := &Item{Level: 1, State: "TODO", Title: "Docs", Properties: map[string]string{"ID": "docs"}}
+	announce := &Item{Level: 1, State: "TODO", Title: "Announce", Properties: map[string]string{"BLOCKER": "ids(docs missing)"}}
+	orgFile := &OrgFile{Items: []*Item{release, docs, announce}}
+
+	isDone := func(state TodoState) bool { return state == "DONE" }
+	tests := []struct {
+		item *Item
+		want []*Item
+	}{
+		{design, nil},
+		{build, nil},
+		{ship, []*Item{build}},
+		{announce, []*Item{docs}},
+		{docs, nil},
+	}
+	idx := orgFile.BlockerIndex()
+	for _, tt := range tests {
+		for name, got := range map[string][]*Item{
+			"OrgFile":      orgFile.OpenBlockers(tt.item, isDone),
+			"BlockerIndex": idx.OpenBlockers(tt.item, isDone),
+		} {
+			if len(got) != len(tt.want) {
+				t.Errorf("%s.OpenBlockers(%s) = %d items; want %d", name, tt.item.Title, len(got), len(tt.want))
+				continue
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s.OpenBlockers(%s)[%d] = %s; want %s", name, tt.item.Title, i, got[i].Title, tt.want[i].Title)
+				}
+			}
+		}
+	}
+}
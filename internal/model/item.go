@@ -14,20 +14,30 @@ const (
 
 // Item represents a single org-mode item (heading)
 type Item struct {
-	Level        int          // Heading level (number of *)
-	State        TodoState    // TODO, PROG, BLOCK, DONE, or empty
-	Priority     Priority     // Priority: A, B, C, or empty
-	Title        string       // The main title text
-	Tags         []string     // Tags for this item (e.g., :work:urgent:)
-	Scheduled    *time.Time
-	Deadline     *time.Time
-	Closed       *time.Time   // Closed timestamp (when task was marked as done)
-	Effort       string       // Effort estimate (e.g., "8h", "2d")
-	Notes        []string     // Notes/content under the heading
-	Children     []*Item      // Sub-items
-	Folded       bool         // Whether the item is folded (hides notes and children)
-	ClockEntries []ClockEntry // Clock in/out entries
-	SourceFile   string       // Source file path (used in multi-file mode)
+	Level             int       // Heading level (number of *)
+	State             TodoState // TODO, PROG, BLOCK, DONE, or empty
+	Priority          Priority  // Priority: A, B, C, or empty
+	Title             string    // The main title text
+	Tags              []string  // Tags for this item (e.g., :work:urgent:)
+	Scheduled         *time.Time
+	Deadline          *time.Time
+	ScheduledEnd      *time.Time        // End of the scheduled time range, or its last day for a range over several days
+	DeadlineEnd       *time.Time        // End of the deadline's time range or range of days
+	ScheduledRepeater string            // Repeater cookie on the scheduled date (e.g. "+1w", ".+1d")
+	DeadlineRepeater  string            // Repeater cookie on the deadline
+	ScheduledDelay    string            // Delay cookie on the scheduled date (e.g. "-2d")
+	DeadlineWarning   string            // Warning period cookie on the deadline (e.g. "-3d")
+	ScheduledHasTime  bool              // Whether the scheduled date has a time of day
+	DeadlineHasTime   bool              // Whether the deadline has a time of day
+	Closed            *time.Time        // Closed timestamp (when task was marked as done)
+	Timestamps        []Timestamp       // Plain active timestamps in the title and notes
+	Effort            string            // Effort estimate (e.g., "8h", "2d")
+	Notes             []string          // Notes/content under the heading
+	Children          []*Item           // Sub-items
+	Folded            bool              // Whether the item is folded (hides notes and children)
+	ClockEntries      []ClockEntry      // Clock in/out entries
+	Properties        map[string]string // Other :PROPERTIES: drawer entries, keyed by upper-case name
+	SourceFile        string            // Source file path (used in multi-file mode)
 }
 
 // OrgFile represents a parsed org-mode file
@@ -57,27 +67,26 @@ func (item *Item) CycleState() {
 	}
 }
 
-// ClockIn starts a new clock entry
-func (item *Item) ClockIn() bool {
+// ClockIn starts a new clock entry at now
+func (item *Item) ClockIn(now time.Time) bool {
 	// Check if already clocked in
 	if item.IsClockedIn() {
 		return false
 	}
 
 	entry := ClockEntry{
-		Start: time.Now(),
+		Start: now,
 		End:   nil,
 	}
 	item.ClockEntries = append(item.ClockEntries, entry)
 	return true
 }
 
-// ClockOut ends the current clock entry
-func (item *Item) ClockOut() bool {
+// ClockOut ends the current clock entry at now
+func (item *Item) ClockOut(now time.Time) bool {
 	// Find the most recent open clock entry
 	for i := len(item.ClockEntries) - 1; i >= 0; i-- {
 		if item.ClockEntries[i].End == nil {
-			now := time.Now()
 			item.ClockEntries[i].End = &now
 			return true
 		}
@@ -136,3 +145,29 @@ func (of *OrgFile) GetAllItems() []*Item {
 	flatten(of.Items)
 	return items
 }
+
+// AllItems returns a flattened list of all items, including the children of folded items
+func (of *OrgFile) AllItems() []*Item {
+	var items []*Item
+	var flatten func([]*Item)
+	flatten = func(list []*Item) {
+		for _, item := range list {
+			items = append(items, item)
+			flatten(item.Children)
+		}
+	}
+	flatten(of.Items)
+	return items
+}
+
+// IsHabit returns true if the item is a habit (:STYLE: habit)
+func (item *Item) IsHabit() bool {
+	return item.GetProperty("STYLE") == "habit"
+}
+
+// IsRepeating returns true if the scheduled date or deadline has a repeater
+func (item *Item) IsRepeating() bool {
+	_, scheduled := ParseRepeater(item.ScheduledRepeater)
+	_, deadline := ParseRepeater(item.DeadlineRepeater)
+	return scheduled || deadline
+}
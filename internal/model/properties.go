@@ -0,0 +1,69 @@
+package model
+
+import "strings"
+
+// GetProperty returns the value of a property from the item's :PROPERTIES: drawer
+// Property names are case-insensitive; returns empty string if not set
+func (item *Item) GetProperty(name string) string {
+	name = strings.ToUpper(name)
+	if name == "EFFORT" {
+		return item.Effort
+	}
+	if item.Properties == nil {
+		return ""
+	}
+	return item.Properties[name]
+}
+
+// SetProperty sets a property, keeping an existing :PROPERTIES: drawer in the notes in sync
+// An empty value removes the property
+func (item *Item) SetProperty(name, value string) {
+	name = strings.ToUpper(name)
+	if name == "EFFORT" {
+		item.Effort = value
+	} else {
+		if item.Properties == nil {
+			item.Properties = make(map[string]string)
+		}
+		if value == "" {
+			delete(item.Properties, name)
+		} else {
+			item.Properties[name] = value
+		}
+	}
+
+	// Find the drawer in the notes; if there is none the writer creates it
+	start, end := -1, -1
+	for i, note := range item.Notes {
+		trimmed := strings.TrimSpace(note)
+		if start < 0 && trimmed == ":PROPERTIES:" {
+			start = i
+		} else if start >= 0 && trimmed == ":END:" {
+			end = i
+			break
+		}
+	}
+	if start < 0 || end < 0 {
+		return
+	}
+
+	prefix := ":" + name + ":"
+	for i := start + 1; i < end; i++ {
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(item.Notes[i])), prefix) {
+			if value == "" {
+				item.Notes = append(item.Notes[:i], item.Notes[i+1:]...)
+			} else {
+				item.Notes[i] = prefix + " " + value
+			}
+			return
+		}
+	}
+
+	if value != "" {
+		updated := make([]string, 0, len(item.Notes)+1)
+		updated = append(updated, item.Notes[:end]...)
+		updated = append(updated, prefix+" "+value)
+		updated = append(updated, item.Notes[end:]...)
+		item.Notes = updated
+	}
+}
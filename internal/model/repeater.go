@@ -0,0 +1,129 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// repeaterPattern matches org repeater cookies: +1w, ++2d, .+1m, and habit ranges like .+2d/4d
+var repeaterPattern = regexp.MustCompile(`^(\+\+|\.\+|\+)(\d+)([hdwmy])(?:/(\d+)([hdwmy]))?$`)
+
+// Repeater represents an org timestamp repeater cookie
+type Repeater struct {
+	Kind     string // "+" (cumulate), "++" (catch-up) or ".+" (restart from today)
+	Value    int
+	Unit     string // h, d, w, m or y
+	MaxValue int    // Upper bound of a habit's interval (e.g. 4 in .+2d/4d), 0 if unset
+	MaxUnit  string
+}
+
+// IsRepeaterCookie reports whether a timestamp field is a repeater cookie, including
+// zero ones like "+0d" that don't repeat
+func IsRepeaterCookie(field string) bool {
+	return repeaterPattern.MatchString(field)
+}
+
+// ParseRepeater parses a repeater cookie such as "+1w" or ".+2d/4d". Zero repeaters
+// like "+0d" don't repeat, as in Org, so they aren't accepted.
+func ParseRepeater(cookie string) (Repeater, bool) {
+	matches := repeaterPattern.FindStringSubmatch(cookie)
+	if matches == nil {
+		return Repeater{}, false
+	}
+
+	value, _ := strconv.Atoi(matches[2])
+	if value == 0 {
+		return Repeater{}, false
+	}
+	r := Repeater{Kind: matches[1], Value: value, Unit: matches[3]}
+	if matches[4] != "" {
+		r.MaxValue, _ = strconv.Atoi(matches[4])
+		r.MaxUnit = matches[5]
+	}
+	return r, true
+}
+
+// String formats the repeater back into org syntax
+func (r Repeater) String() string {
+	s := fmt.Sprintf("%s%d%s", r.Kind, r.Value, r.Unit)
+	if r.MaxValue > 0 {
+		s += fmt.Sprintf("/%d%s", r.MaxValue, r.MaxUnit)
+	}
+	return s
+}
+
+// addInterval adds n repeater units to t
+func addInterval(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour)
+	case "d":
+		return t.AddDate(0, 0, n)
+	case "w":
+		return t.AddDate(0, 0, 7*n)
+	case "m":
+		return t.AddDate(0, n, 0)
+	case "y":
+		return t.AddDate(n, 0, 0)
+	}
+	return t
+}
+
+// Interval returns t shifted by one repeat interval
+func (r Repeater) Interval(t time.Time) time.Time {
+	return addInterval(t, r.Value, r.Unit)
+}
+
+// MaxInterval returns t shifted by the habit's maximum interval, or by the
+// normal interval if no maximum is set
+func (r Repeater) MaxInterval(t time.Time) time.Time {
+	if r.MaxValue > 0 {
+		return addInterval(t, r.MaxValue, r.MaxUnit)
+	}
+	return r.Interval(t)
+}
+
+// Next returns the next occurrence of a timestamp after the task was completed at now
+func (r Repeater) Next(current time.Time, now time.Time) time.Time {
+	if r.Value <= 0 {
+		return current
+	}
+	switch r.Kind {
+	case ".+":
+		// Restart from today, keeping the time of day
+		base := time.Date(now.Year(), now.Month(), now.Day(), current.Hour(), current.Minute(), 0, 0, current.Location())
+		return r.Interval(base)
+	case "++":
+		// Shift by whole intervals until the date is in the future
+		next := r.Interval(current)
+		for !next.After(now) {
+			next = r.Interval(next)
+		}
+		return next
+	default:
+		return r.Interval(current)
+	}
+}
+
+// AdvanceRepeats moves the repeating scheduled date and deadline to their next occurrence
+// after the task was completed at now, moving the end of their ranges along with them
+func (item *Item) AdvanceRepeats(now time.Time) {
+	item.Scheduled, item.ScheduledEnd = advanceRange(item.Scheduled, item.ScheduledEnd, item.ScheduledRepeater, now)
+	item.Deadline, item.DeadlineEnd = advanceRange(item.Deadline, item.DeadlineEnd, item.DeadlineRepeater, now)
+}
+
+// advanceRange shifts a date and the end of its range by its repeater cookie
+func advanceRange(start, end *time.Time, cookie string, now time.Time) (*time.Time, *time.Time) {
+	repeater, ok := ParseRepeater(cookie)
+	if !ok || start == nil {
+		return start, end
+	}
+	next := repeater.Next(*start, now)
+	if end != nil {
+		nextEnd := end.Add(next.Sub(*start))
+		end = &nextEnd
+	}
+	return &next, end
+}
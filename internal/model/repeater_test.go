@@ -0,0 +1,92 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d, h, min int) time.Time {
+	return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+}
+
+func TestParseRepeater(t *testing.T) {
+	tests := []struct {
+		cookie string
+		want   Repeater
+		ok     bool
+	}{
+		{"+1w", Repeater{Kind: "+", Value: 1, Unit: "w"}, true},
+		{"++2d", Repeater{Kind: "++", Value: 2, Unit: "d"}, true},
+		{".+1m", Repeater{Kind: ".+", Value: 1, Unit: "m"}, true},
+		{".+2d/4d", Repeater{Kind: ".+", Value: 2, Unit: "d", MaxValue: 4, MaxUnit: "d"}, true},
+		{"+0d", Repeater{}, false},
+		{"++0d", Repeater{}, false},
+		{"-3d", Repeater{}, false},
+		{"1w", Repeater{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRepeater(tt.cookie)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseRepeater(%q) = %+v, %v; want %+v, %v", tt.cookie, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRepeaterNext(t *testing.T) {
+	now := date(2025, 1, 20, 12, 0)
+	tests := []struct {
+		repeater Repeater
+		current  time.Time
+		want     time.Time
+	}{
+		// + shifts once, even if the result is still in the past
+		{Repeater{Kind: "+", Value: 1, Unit: "w"}, date(2025, 1, 6, 9, 0), date(2025, 1, 13, 9, 0)},
+		// ++ shifts until the date is in the future
+		{Repeater{Kind: "++", Value: 1, Unit: "w"}, date(2025, 1, 6, 9, 0), date(2025, 1, 27, 9, 0)},
+		{Repeater{Kind: "++", Value: 1, Unit: "d"}, date(2025, 1, 20, 9, 0), date(2025, 1, 21, 9, 0)},
+		// .+ restarts from today, keeping the time of day
+		{Repeater{Kind: ".+", Value: 2, Unit: "d"}, date(2025, 1, 6, 9, 0), date(2025, 1, 22, 9, 0)},
+		{Repeater{Kind: "+", Value: 3, Unit: "h"}, date(2025, 1, 20, 9, 0), date(2025, 1, 20, 12, 0)},
+		// A zero interval leaves the date alone instead of looping forever
+		{Repeater{Kind: "++", Value: 0, Unit: "d"}, date(2025, 1, 6, 9, 0), date(2025, 1, 6, 9, 0)},
+	}
+	for _, tt := range tests {
+		if got := tt.repeater.Next(tt.current, now); !got.Equal(tt.want) {
+			t.Errorf("%s.Next(%v) = %v; want %v", tt.repeater, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestAdvanceRepeats(t *testing.T) {
+	scheduled := date(2025, 1, 6, 14, 0)
+	scheduledEnd := date(2025, 1, 6, 15, 30)
+	deadline := date(2025, 1, 10, 0, 0)
+	item := &Item{
+		Scheduled:         &scheduled,
+		ScheduledEnd:      &scheduledEnd,
+		ScheduledRepeater: "+1w",
+		Deadline:          &deadline,
+		DeadlineRepeater:  "++0d",
+	}
+
+	item.AdvanceRepeats(date(2025, 1, 20, 12, 0))
+
+	if want := date(2025, 1, 13, 14, 0); !item.Scheduled.Equal(want) {
+		t.Errorf("scheduled = %v; want %v", item.Scheduled, want)
+	}
+	if want := date(2025, 1, 13, 15, 30); !item.ScheduledEnd.Equal(want) {
+		t.Errorf("scheduled end = %v; want %v", item.ScheduledEnd, want)
+	}
+	if !item.Deadline.Equal(deadline) {
+		t.Errorf("deadline with a zero repeater moved to %v", item.Deadline)
+	}
+}
+
+func TestIsRepeatingIgnoresZeroRepeaters(t *testing.T) {
+	if (&Item{ScheduledRepeater: "+0d"}).IsRepeating() {
+		t.Error("an item with a +0d repeater is repeating")
+	}
+	if !(&Item{DeadlineRepeater: ".+1d"}).IsRepeating() {
+		t.Error("an item with a .+1d repeater isn't repeating")
+	}
+}
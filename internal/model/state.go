@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // TodoState represents the state of a todo item
 type TodoState string
 
@@ -10,3 +12,11 @@ const (
 	StateDONE  TodoState = "DONE"
 	StateNone  TodoState = ""
 )
+
+// StateChange represents a logged transition between todo states
+type StateChange struct {
+	From TodoState
+	To   TodoState
+	Time time.Time
+	Note string // Optional note entered when the change was made
+}
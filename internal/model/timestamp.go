@@ -0,0 +1,67 @@
+package model
+
+import "time"
+
+// Timestamp is an active timestamp. Plain ones in a heading or its notes are appointments
+// on that day.
+type Timestamp struct {
+	Start    time.Time
+	End      *time.Time // End of a time range or range of days, nil if there is none
+	Repeater string     // Repeater cookie, e.g. "+1w"
+	Warning  string     // Warning (or delay) cookie, e.g. "-3d"
+	HasTime  bool       // Whether it has a time of day, rather than being for the whole day
+}
+
+// ScheduledTimestamp returns the item's scheduled date with its range, cookies and whether
+// it has a time, or a zero Timestamp if it isn't scheduled
+func (item *Item) ScheduledTimestamp() Timestamp {
+	if item.Scheduled == nil {
+		return Timestamp{}
+	}
+	return Timestamp{
+		Start:    *item.Scheduled,
+		End:      item.ScheduledEnd,
+		Repeater: item.ScheduledRepeater,
+		Warning:  item.ScheduledDelay,
+		HasTime:  item.ScheduledHasTime,
+	}
+}
+
+// DeadlineTimestamp returns the item's deadline with its range, cookies and whether it
+// has a time, or a zero Timestamp if it has no deadline
+func (item *Item) DeadlineTimestamp() Timestamp {
+	if item.Deadline == nil {
+		return Timestamp{}
+	}
+	return Timestamp{
+		Start:    *item.Deadline,
+		End:      item.DeadlineEnd,
+		Repeater: item.DeadlineRepeater,
+		Warning:  item.DeadlineWarning,
+		HasTime:  item.DeadlineHasTime,
+	}
+}
+
+// Occurrence returns the first occurrence of the timestamp that isn't over before from,
+// following its repeater. ok is false if the timestamp is in the past.
+func (ts Timestamp) Occurrence(from time.Time) (occurrence Timestamp, ok bool) {
+	start, end := ts.Start, ts.Start
+	if ts.End != nil {
+		end = *ts.End
+	}
+	repeater, repeats := ParseRepeater(ts.Repeater)
+	for end.Before(from) {
+		if !repeats {
+			return Timestamp{}, false
+		}
+		next := repeater.Interval(start)
+		end = end.Add(next.Sub(start))
+		start = next
+	}
+
+	occurrence = Timestamp{Start: start, Repeater: ts.Repeater, HasTime: ts.HasTime}
+	if ts.End != nil {
+		occurrence.End = &end
+	}
+	return occurrence, true
+}
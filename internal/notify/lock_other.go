@@ -0,0 +1,10 @@
+//go:build !unix
+
+package notify
+
+import "os"
+
+// lockFile is a no-op on platforms without flock
+func lockFile(file *os.File) error {
+	return nil
+}
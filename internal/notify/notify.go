@@ -0,0 +1,192 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/hooks"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// Reminder is a reminder that is due to be sent. It holds everything needed to send it,
+// so that it can be sent in the background while the item changes.
+type Reminder struct {
+	Key     string // Identifies the reminder, so that it is only sent once
+	Title   string // Title of the item
+	Message string // What the reminder is about, e.g. "Scheduled at 14:00"
+	At      time.Time
+	hook    *hooks.Invocation // Reminder hook to show it with, nil for the terminal
+}
+
+// Due returns the reminders that should have been sent by now: timed scheduled items and
+// appointments that start within the configured number of minutes, and deadlines due
+// today once the configured time of day has passed. Done items are left out. Reminders
+// that were missed are dropped once their item has started, or the day of the deadline
+// is over.
+func Due(orgFile *model.OrgFile, cfg *config.Config, now time.Time) []Reminder {
+	lead := time.Duration(cfg.Notify.MinutesBefore) * time.Minute
+	deadlineTime, err := time.Parse("15:04", cfg.Notify.DeadlineTime)
+	if err != nil {
+		deadlineTime, _ = time.Parse("15:04", config.DefaultConfig().Notify.DeadlineTime)
+	}
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var reminders []Reminder
+	add := func(item *model.Item, kind, message string, at, from, until time.Time) {
+		if now.Before(from) || !now.Before(until) {
+			return
+		}
+		file := item.SourceFile
+		if file == "" {
+			file = orgFile.Path
+		}
+		path := file
+		if abs, err := filepath.Abs(file); err == nil {
+			path = abs
+		}
+		extra := map[string]string{"message": message, "at": at.Format("2006-01-02 15:04")}
+		reminders = append(reminders, Reminder{
+			Key:     strings.Join([]string{kind, path, itemKey(item), at.Format(time.RFC3339)}, "|"),
+			Title:   item.Title,
+			Message: message,
+			At:      at,
+			hook:    hooks.Prepare(cfg, hooks.Reminder, item, file, extra),
+		})
+	}
+
+	for _, item := range orgFile.AllItems() {
+		if cfg.IsDoneState(string(item.State)) {
+			continue
+		}
+		// Timed items are reminded of until a minute after they start, so that a check
+		// every minute doesn't miss those without a lead time
+		if item.Scheduled != nil && item.ScheduledHasTime {
+			at := *item.Scheduled
+			add(item, "scheduled", "Scheduled at "+formatTime(at, item.ScheduledEnd), at, at.Add(-lead), at.Add(time.Minute))
+		}
+		for _, ts := range item.Timestamps {
+			if occurrence, ok := ts.Occurrence(startOfDay); ok && occurrence.HasTime {
+				at := occurrence.Start
+				add(item, "appointment", "At "+formatTime(at, occurrence.End), at, at.Add(-lead), at.Add(time.Minute))
+			}
+		}
+		if item.Deadline != nil && model.DaysUntil(now, *item.Deadline) == 0 {
+			message := "Due today"
+			if item.DeadlineHasTime {
+				message += " at " + item.Deadline.Format("15:04")
+			}
+			from := startOfDay.Add(time.Duration(deadlineTime.Hour())*time.Hour + time.Duration(deadlineTime.Minute())*time.Minute)
+			add(item, "deadline", message, *item.Deadline, from, startOfDay.AddDate(0, 0, 1))
+		}
+	}
+	return reminders
+}
+
+// Deliver sends reminders and records them as sent. The reminder hook shows them if there
+// is one, otherwise the terminal is notified by writing to w. It returns the reminders
+// that were sent and the first error.
+//
+// The record is locked while the reminders are sent, and each one is recorded before it
+// is sent, so that processes running at the same time can't both send it. Reminders
+// another process sent in the meantime are skipped.
+func Deliver(reminders []Reminder, now time.Time, w io.Writer) ([]Reminder, error) {
+	if len(reminders) == 0 {
+		return nil, nil
+	}
+	unlock, err := lockSent()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	sent, err := loadSent()
+	if err != nil {
+		return nil, err
+	}
+	var unsent []Reminder
+	for _, reminder := range reminders {
+		if !sent.has(reminder.Key) {
+			unsent = append(unsent, reminder)
+			sent.mark(reminder.Key, now)
+		}
+	}
+	if len(unsent) == 0 {
+		return nil, nil
+	}
+	if err := sent.save(now); err != nil {
+		return nil, err
+	}
+
+	var delivered []Reminder
+	var firstErr error
+	for _, reminder := range unsent {
+		if err := reminder.send(w); err != nil {
+			// Try again on the next check
+			sent.unmark(reminder.Key)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		delivered = append(delivered, reminder)
+	}
+	if len(delivered) < len(unsent) {
+		if err := sent.save(now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return delivered, firstErr
+}
+
+// Check sends the reminders that are due and haven't been sent yet
+func Check(orgFile *model.OrgFile, cfg *config.Config, now time.Time, w io.Writer) ([]Reminder, error) {
+	return Deliver(Due(orgFile, cfg, now), now, w)
+}
+
+// Hooked returns true if the reminder is shown with the reminder hook rather than in the
+// terminal
+func (r Reminder) Hooked() bool {
+	return r.hook != nil
+}
+
+// send shows a reminder with the reminder hook, or with a bell and an OSC 9 notification
+// written to the terminal
+func (r Reminder) send(w io.Writer) error {
+	if r.hook != nil {
+		return r.hook.Run()
+	}
+	_, err := fmt.Fprintf(w, "\a\x1b]9;%s: %s\x07", sanitize(r.Title), sanitize(r.Message))
+	return err
+}
+
+// sanitize keeps control characters from ending the escape sequence early
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// itemKey identifies an item across reloads: by its ID if it has one, or its title
+func itemKey(item *model.Item) string {
+	if id := item.GetProperty("ID"); id != "" {
+		return "id:" + id
+	}
+	return item.Title
+}
+
+// formatTime formats the time an item starts, and the end of its time range if it ends
+// the same day
+func formatTime(at time.Time, end *time.Time) string {
+	s := at.Format("15:04")
+	if end != nil && model.DaysUntil(at, *end) == 0 {
+		s += end.Format("-15:04")
+	}
+	return s
+}
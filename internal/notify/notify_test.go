@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+func parseOrg(t *testing.T, content string) (*model.OrgFile, *config.Config) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Timezone = "UTC"
+	orgFile, err := parser.Parse(strings.NewReader(content), "test.org", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return orgFile, cfg
+}
+
+func at(hour, min int) time.Time {
+	return time.Date(2025, 1, 6, hour, min, 0, 0, time.UTC)
+}
+
+func TestDue(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		now     time.Time
+		want    string // Message of the reminder, "" for none
+	}{
+		{"before lead time", "* TODO Call\nSCHEDULED: <2025-01-06 Mon 14:00>\n", at(13, 49), ""},
+		{"within lead time", "* TODO Call\nSCHEDULED: <2025-01-06 Mon 14:00>\n", at(13, 50), "Scheduled at 14:00"},
+		{"at start", "* TODO Call\nSCHEDULED: <2025-01-06 Mon 14:00-15:00>\n", at(14, 0), "Scheduled at 14:00-15:00"},
+		{"after start", "* TODO Call\nSCHEDULED: <2025-01-06 Mon 14:00>\n", at(14, 1), ""},
+		{"untimed scheduled", "* TODO Call\nSCHEDULED: <2025-01-06 Mon>\n", at(9, 0), ""},
+		{"appointment", "* Meeting\n<2025-01-06 Mon 14:00>\n", at(13, 55), "At 14:00"},
+		{"deadline before time of day", "* TODO Report\nDEADLINE: <2025-01-06 Mon>\n", at(8, 59), ""},
+		{"deadline at time of day", "* TODO Report\nDEADLINE: <2025-01-06 Mon>\n", at(9, 0), "Due today"},
+		{"timed deadline", "* TODO Report\nDEADLINE: <2025-01-06 Mon 17:00>\n", at(23, 59), "Due today at 17:00"},
+		{"deadline tomorrow", "* TODO Report\nDEADLINE: <2025-01-07 Tue>\n", at(12, 0), ""},
+		{"done item", "* DONE Call\nSCHEDULED: <2025-01-06 Mon 14:00>\n", at(13, 55), ""},
+		{"done deadline", "* DONE Report\nDEADLINE: <2025-01-06 Mon>\n", at(12, 0), ""},
+	}
+	for _, tt := range tests {
+		orgFile, cfg := parseOrg(t, tt.content)
+		reminders := Due(orgFile, cfg, tt.now)
+		got := ""
+		if len(reminders) > 0 {
+			got = reminders[0].Message
+		}
+		if len(reminders) > 1 || got != tt.want {
+			t.Errorf("%s: Due = %d reminders (%q); want %q", tt.name, len(reminders), got, tt.want)
+		}
+	}
+}
+
+func TestDueConfiguredTimes(t *testing.T) {
+	orgFile, cfg := parseOrg(t, "* TODO Call\nSCHEDULED: <2025-01-06 Mon 14:00>\n* TODO Report\nDEADLINE: <2025-01-06 Mon>\n")
+	cfg.Notify.MinutesBefore = 30
+	cfg.Notify.DeadlineTime = "13:30"
+	tests := []struct {
+		now  time.Time
+		want int
+	}{
+		{at(13, 29), 0},
+		{at(13, 30), 2},
+	}
+	for _, tt := range tests {
+		if got := len(Due(orgFile, cfg, tt.now)); got != tt.want {
+			t.Errorf("Due at %s = %d reminders; want %d", tt.now.Format("15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	// Keep the record of sent reminders away from the user's
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	orgFile, cfg := parseOrg(t, "* TODO Call\nSCHEDULED: <2025-01-06 Mon 14:00>\n* TODO Report\nDEADLINE: <2025-01-06 Mon>\n")
+	tests := []struct {
+		name string
+		now  time.Time
+		want []string // Titles of the reminders sent
+	}{
+		{"deadline", at(9, 0), []string{"Report"}},
+		{"deadline again", at(9, 1), nil},
+		{"scheduled", at(13, 50), []string{"Call"}},
+		{"scheduled again", at(13, 55), nil},
+		{"both again", at(14, 0), nil},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		sent, err := Deliver(Due(orgFile, cfg, tt.now), tt.now, &out)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var titles []string
+		for _, reminder := range sent {
+			titles = append(titles, reminder.Title)
+			if !strings.Contains(out.String(), reminder.Title+": "+reminder.Message) {
+				t.Errorf("%s: terminal notification %q doesn't mention %q", tt.name, out.String(), reminder.Title)
+			}
+		}
+		if strings.Join(titles, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: Deliver sent %v; want %v", tt.name, titles, tt.want)
+		}
+		if len(tt.want) == 0 && out.Len() > 0 {
+			t.Errorf("%s: Deliver wrote %q; want nothing", tt.name, out.String())
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// keepSent is how long sent reminders are remembered
+const keepSent = 7 * 24 * time.Hour
+
+// sentReminders records when each reminder was sent. It is kept in a file shared by the
+// TUI and `org notify`, so that a reminder isn't sent twice when both are running.
+type sentReminders struct {
+	path string
+	sent map[string]time.Time
+}
+
+// sentPath returns the path of the file that records sent reminders
+func sentPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "org", "reminders.json"), nil
+}
+
+// lockSent takes a lock shared by every process sending reminders, so that checking,
+// recording and sending a reminder happen in one go. The record itself is replaced on
+// every save, so the lock is taken on a file next to it. The returned function releases
+// the lock.
+func lockSent() (unlock func(), err error) {
+	path, err := sentPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() { file.Close() }, nil
+}
+
+// loadSent reads the reminders that were sent, starting empty if none were
+func loadSent() (*sentReminders, error) {
+	path, err := sentPath()
+	if err != nil {
+		return nil, err
+	}
+	s := &sentReminders{path: path, sent: make(map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.sent); err != nil {
+		// Start over rather than getting stuck on a damaged file
+		s.sent = make(map[string]time.Time)
+	}
+	return s, nil
+}
+
+func (s *sentReminders) has(key string) bool {
+	_, ok := s.sent[key]
+	return ok
+}
+
+func (s *sentReminders) mark(key string, now time.Time) {
+	s.sent[key] = now
+}
+
+func (s *sentReminders) unmark(key string) {
+	delete(s.sent, key)
+}
+
+// save writes the sent reminders, forgetting those sent long ago
+func (s *sentReminders) save(now time.Time) error {
+	for key, at := range s.sent {
+		if now.Sub(at) > keepSent {
+			delete(s.sent, key)
+		}
+	}
+	data, err := json.MarshalIndent(s.sent, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a reader never sees half a file
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "reminders-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -2,29 +2,163 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
+
+	"github.com/rwejlgaard/org/internal/model"
 )
 
-// parseOrgDate parses org-mode date format
-func parseOrgDate(dateStr string) (time.Time, error) {
+// parseOrgDate parses org-mode date format as a wall clock time in loc, reporting whether
+// the date has a time of day
+func parseOrgDate(dateStr string, loc *time.Location) (time.Time, bool, error) {
 	// Org-mode format: 2024-01-15 Mon 10:00
-	formats := []string{
-		"2006-01-02 Mon 15:04",
-		"2006-01-02 Mon",
-		"2006-01-02",
+	formats := []struct {
+		layout  string
+		hasTime bool
+	}{
+		{"2006-01-02 Mon 15:04", true},
+		{"2006-01-02 Mon", false},
+		{"2006-01-02", false},
 	}
 
 	for _, format := range formats {
-		if t, err := time.Parse(format, dateStr); err == nil {
-			return t, nil
+		if t, err := time.ParseInLocation(format.layout, dateStr, loc); err == nil {
+			return t, format.hasTime, nil
+		}
+	}
+
+	return time.Time{}, false, fmt.Errorf("unable to parse date: %s", dateStr)
+}
+
+// splitTimestampCookies separates repeater (+1w, ++1d, .+1d) and warning (-3d) cookies
+// from the date part of a timestamp body like "2024-01-15 Mon .+1d -2d"
+func splitTimestampCookies(body string) (date string, repeater string, warning string) {
+	var dateParts []string
+	for _, field := range strings.Fields(body) {
+		if model.IsRepeaterCookie(field) {
+			repeater = field
+		} else if strings.HasPrefix(field, "-") && len(field) > 1 {
+			warning = field
+		} else {
+			dateParts = append(dateParts, field)
+		}
+	}
+	return strings.Join(dateParts, " "), repeater, warning
+}
+
+// parseOrgTimestamp parses an active timestamp body into its date, the end of a time range
+// like "14:00-15:00" if there is one, and its repeater and warning cookies
+func parseOrgTimestamp(body string, loc *time.Location) (model.Timestamp, error) {
+	date, repeater, warning := splitTimestampCookies(body)
+	rangeEnd := ""
+	if matches := timeRangeEndPattern.FindStringSubmatch(date); matches != nil {
+		date = strings.TrimSuffix(date, matches[0]) + matches[1]
+		rangeEnd = matches[2]
+	}
+	t, hasTime, err := parseOrgDate(date, loc)
+	if err != nil {
+		return model.Timestamp{}, err
+	}
+
+	ts := model.Timestamp{Start: t, Repeater: repeater, Warning: warning, HasTime: hasTime}
+	if clock, err := time.Parse("15:04", rangeEnd); err == nil {
+		end := time.Date(t.Year(), t.Month(), t.Day(), clock.Hour(), clock.Minute(), 0, 0, t.Location())
+		if end.After(t) {
+			ts.End = &end
+		}
+	}
+	return ts, nil
+}
+
+// parsePlanningTimestamp parses the timestamp of a planning line, along with the second
+// timestamp of a range over several days like <2025-01-06 Mon>--<2025-01-08 Wed>
+func parsePlanningTimestamp(body, endBody string, loc *time.Location) (model.Timestamp, error) {
+	ts, err := parseOrgTimestamp(body, loc)
+	if err != nil || endBody == "" {
+		return ts, err
+	}
+	if last, err := parseOrgTimestamp(endBody, loc); err == nil && last.Start.After(ts.Start) {
+		ts.End = &last.Start
+	}
+	return ts, nil
+}
+
+// timeRangeEndPattern matches a time range like "14:00-15:00" at the end of a timestamp
+var timeRangeEndPattern = regexp.MustCompile(`(\d{1,2}:\d{2})-(\d{1,2}:\d{2})$`)
+
+// FormatTimestampRange formats an active timestamp spanning start to end, e.g.
+// <2025-01-06 Mon 14:00-15:00>, or <2025-01-06 Mon>--<2025-01-08 Wed> for ranges over several days
+func FormatTimestampRange(start, end time.Time, withTime bool) string {
+	format := FormatOrgDate
+	if withTime {
+		format = FormatOrgDateTime
+	}
+
+	sameDay := start.Year() == end.Year() && start.YearDay() == end.YearDay()
+	switch {
+	case sameDay && withTime && !end.Equal(start):
+		return "<" + FormatOrgDateTime(start) + "-" + end.Format("15:04") + ">"
+	case sameDay || end.Before(start):
+		return "<" + format(start) + ">"
+	default:
+		return "<" + format(start) + ">--<" + format(end) + ">"
+	}
+}
+
+// FormatPlanningTimestamp formats a planning date for display, with its time, the end of
+// its range and its repeater and warning cookies, e.g. "2025-01-06 Mon 14:00-15:00 +1w -2d".
+// A range over several days is shown as "2025-01-06 Mon--2025-01-08 Wed".
+func FormatPlanningTimestamp(ts model.Timestamp) string {
+	start, last := planningTimestampParts(ts)
+	if last != "" {
+		return start + "--" + last
+	}
+	return start
+}
+
+// FormatPlanning formats a planning date as an org timestamp, e.g.
+// "<2025-01-06 Mon 14:00-15:00 +1w>" or "<2025-01-06 Mon>--<2025-01-08 Wed>"
+func FormatPlanning(ts model.Timestamp) string {
+	start, last := planningTimestampParts(ts)
+	if last != "" {
+		return "<" + start + ">--<" + last + ">"
+	}
+	return "<" + start + ">"
+}
+
+// planningTimestampParts formats the body of a planning timestamp and, for a range over
+// several days, the body of its second timestamp
+func planningTimestampParts(ts model.Timestamp) (start, last string) {
+	t, end := ts.Start, ts.End
+	start = formatPlanningDate(t, ts.HasTime)
+	if end != nil && end.After(t) {
+		if end.Year() == t.Year() && end.YearDay() == t.YearDay() {
+			start = FormatOrgDateTime(t) + "-" + end.Format("15:04")
+		} else {
+			last = formatPlanningDate(*end, ts.HasTime)
 		}
 	}
+	// Cookies belong in the first timestamp of a range
+	if ts.Repeater != "" {
+		start += " " + ts.Repeater
+	}
+	if ts.Warning != "" {
+		start += " " + ts.Warning
+	}
+	return start, last
+}
 
-	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
+// formatPlanningDate formats a planning date, with the time of day if it has one
+func formatPlanningDate(t time.Time, hasTime bool) string {
+	if hasTime {
+		return FormatOrgDateTime(t)
+	}
+	return FormatOrgDate(t)
 }
 
-// parseClockTimestamp parses org-mode clock timestamp format
-func parseClockTimestamp(timestampStr string) (time.Time, error) {
+// parseClockTimestamp parses org-mode clock timestamp format as a wall clock time in loc
+func parseClockTimestamp(timestampStr string, loc *time.Location) (time.Time, error) {
 	// Org-mode clock format: [2024-01-15 Mon 10:00]
 	formats := []string{
 		"2006-01-02 Mon 15:04",
@@ -32,7 +166,7 @@ func parseClockTimestamp(timestampStr string) (time.Time, error) {
 	}
 
 	for _, format := range formats {
-		if t, err := time.Parse(format, timestampStr); err == nil {
+		if t, err := time.ParseInLocation(format, timestampStr, loc); err == nil {
 			return t, nil
 		}
 	}
@@ -49,3 +183,8 @@ func formatClockTimestamp(t time.Time) string {
 func FormatOrgDate(t time.Time) string {
 	return t.Format("2006-01-02 Mon")
 }
+
+// FormatOrgDateTime formats a time as org-mode date with time of day
+func FormatOrgDateTime(t time.Time) string {
+	return formatClockTimestamp(t)
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+func TestParseOrgTimestamp(t *testing.T) {
+	tests := []struct {
+		body     string
+		start    string
+		end      string
+		hasTime  bool
+		repeater string
+		warning  string
+	}{
+		{"2025-01-06 Mon", "2025-01-06 00:00", "", false, "", ""},
+		{"2025-01-06 Mon 00:00", "2025-01-06 00:00", "", true, "", ""},
+		{"2025-01-06 Mon 14:00-15:30", "2025-01-06 14:00", "2025-01-06 15:30", true, "", ""},
+		{"2025-01-06 Mon 09:00 ++1w -2d", "2025-01-06 09:00", "", true, "++1w", "-2d"},
+		{"2025-01-06 Mon +0d", "2025-01-06 00:00", "", false, "+0d", ""},
+	}
+	for _, tt := range tests {
+		ts, err := parseOrgTimestamp(tt.body, time.UTC)
+		if err != nil {
+			t.Errorf("parseOrgTimestamp(%q): %v", tt.body, err)
+			continue
+		}
+		if got := ts.Start.Format("2006-01-02 15:04"); got != tt.start {
+			t.Errorf("parseOrgTimestamp(%q) start = %s; want %s", tt.body, got, tt.start)
+		}
+		end := ""
+		if ts.End != nil {
+			end = ts.End.Format("2006-01-02 15:04")
+		}
+		if end != tt.end || ts.HasTime != tt.hasTime || ts.Repeater != tt.repeater || ts.Warning != tt.warning {
+			t.Errorf("parseOrgTimestamp(%q) = end %q, time %v, %q, %q; want end %q, time %v, %q, %q",
+				tt.body, end, ts.HasTime, ts.Repeater, ts.Warning, tt.end, tt.hasTime, tt.repeater, tt.warning)
+		}
+	}
+}
+
+func TestUpdatePlanningKeepsTimes(t *testing.T) {
+	content := "* TODO Midnight call\n" +
+		"SCHEDULED: <2025-01-06 Mon 00:00 +1w> DEADLINE: <2025-01-08 Wed>\n" +
+		"* TODO Trip\n" +
+		"SCHEDULED: <2025-01-06 Mon 10:00>--<2025-01-08 Wed 18:00>\n"
+	orgFile, err := Parse(strings.NewReader(content), "test.org", config.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := orgFile.Items[0]
+	moved := call.Scheduled.AddDate(0, 0, 7)
+	call.Scheduled = &moved
+	UpdatePlanning(call)
+	if want := "SCHEDULED: <2025-01-13 Mon 00:00 +1w> DEADLINE: <2025-01-08 Wed>"; call.Notes[0] != want {
+		t.Errorf("planning line = %q; want %q", call.Notes[0], want)
+	}
+
+	trip := orgFile.Items[1]
+	UpdatePlanning(trip)
+	if want := "SCHEDULED: <2025-01-06 Mon 10:00>--<2025-01-08 Wed 18:00>"; trip.Notes[0] != want {
+		t.Errorf("planning line = %q; want %q", trip.Notes[0], want)
+	}
+}
+
+func TestFormatPlanningWithoutTime(t *testing.T) {
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local)
+	if got, want := FormatPlanning(model.Timestamp{Start: day}), "<2025-01-06 Mon>"; got != want {
+		t.Errorf("FormatPlanning = %q; want %q", got, want)
+	}
+	if got, want := FormatPlanning(model.Timestamp{Start: day, HasTime: true}), "<2025-01-06 Mon 00:00>"; got != want {
+		t.Errorf("FormatPlanning = %q; want %q", got, want)
+	}
+}
+
+func TestParseInConfiguredTimezone(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Timezone = "America/New_York"
+	loc, err := cfg.Location()
+	if err != nil {
+		t.Skip("no timezone data:", err)
+	}
+	content := "* TODO Call\n" +
+		"SCHEDULED: <2025-01-06 Mon 09:00>\n" +
+		":LOGBOOK:\n" +
+		"CLOCK: [2025-01-05 Sun 10:00]--[2025-01-05 Sun 11:00] =>  1:00\n" +
+		":END:\n"
+	orgFile, err := Parse(strings.NewReader(content), "test.org", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := orgFile.Items[0]
+	if want := time.Date(2025, 1, 6, 9, 0, 0, 0, loc); !item.Scheduled.Equal(want) {
+		t.Errorf("scheduled = %v; want %v", item.Scheduled, want)
+	}
+	if want := time.Date(2025, 1, 5, 10, 0, 0, 0, loc); len(item.ClockEntries) != 1 || !item.ClockEntries[0].Start.Equal(want) {
+		t.Errorf("clock entries = %v; want one starting at %v", item.ClockEntries, want)
+	}
+}
@@ -0,0 +1,22 @@
+package parser
+
+import "os"
+
+// createLocked opens a file for writing and takes an exclusive lock on it before truncating,
+// so that other writers such as `org serve` wait instead of interleaving their output.
+// The lock is released when the file is closed.
+func createLocked(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file, true); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
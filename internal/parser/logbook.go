@@ -0,0 +1,212 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// stateChangePattern matches org's state change log line, e.g.
+// - State "DONE"       from "PROG"       [2025-01-05 Sun 10:12]
+var stateChangePattern = regexp.MustCompile(`^\s*- State "([^"]*)"\s+from(?:\s+"([^"]*)")?\s+\[([^\]]+)\]\s*(\\\\)?\s*$`)
+
+// FormatStateChange formats a state change as an org LOGBOOK line
+// The line ends with a line break marker when a note follows it
+func FormatStateChange(change model.StateChange) string {
+	from := ""
+	if change.From != model.StateNone {
+		from = `"` + string(change.From) + `"`
+	}
+	line := fmt.Sprintf("- State %-12s from %-12s [%s]", `"`+string(change.To)+`"`, from, formatClockTimestamp(change.Time))
+	if change.Note != "" {
+		line += ` \\`
+	}
+	return line
+}
+
+// LogStateChange records a state change at the top of the item's LOGBOOK drawer
+func LogStateChange(item *model.Item, change model.StateChange) {
+	lines := []string{FormatStateChange(change)}
+	if change.Note != "" {
+		for _, noteLine := range strings.Split(change.Note, "\n") {
+			lines = append(lines, "  "+noteLine)
+		}
+	}
+	AddLogbookLines(item, lines)
+}
+
+// AddLogbookLines inserts lines at the top of the item's LOGBOOK drawer in its notes,
+// creating the drawer after any planning lines and PROPERTIES drawer if needed
+func AddLogbookLines(item *model.Item, lines []string) {
+	for i, note := range item.Notes {
+		if logbookDrawerStart.MatchString(note) {
+			updated := make([]string, 0, len(item.Notes)+len(lines))
+			updated = append(updated, item.Notes[:i+1]...)
+			updated = append(updated, lines...)
+			updated = append(updated, item.Notes[i+1:]...)
+			item.Notes = updated
+			return
+		}
+	}
+
+	// No drawer yet - clock entries are reconciled into it by the writer
+	insertAt := logbookInsertIndex(item.Notes)
+	drawer := append([]string{":LOGBOOK:"}, lines...)
+	drawer = append(drawer, ":END:")
+
+	updated := make([]string, 0, len(item.Notes)+len(drawer))
+	updated = append(updated, item.Notes[:insertAt]...)
+	updated = append(updated, drawer...)
+	updated = append(updated, item.Notes[insertAt:]...)
+	item.Notes = updated
+}
+
+// logbookInsertIndex returns the position in notes where a new LOGBOOK drawer belongs:
+// after the planning lines and the PROPERTIES drawer, as org expects
+func logbookInsertIndex(notes []string) int {
+	i := 0
+	for i < len(notes) {
+		trimmed := strings.TrimSpace(notes[i])
+		if strings.HasPrefix(trimmed, "SCHEDULED:") || strings.HasPrefix(trimmed, "DEADLINE:") || strings.HasPrefix(trimmed, "CLOSED:") {
+			i++
+			continue
+		}
+		if propertiesDrawerStart.MatchString(notes[i]) {
+			for i < len(notes) && !drawerEnd.MatchString(notes[i]) {
+				i++
+			}
+			if i < len(notes) {
+				i++ // Skip :END:
+			}
+			continue
+		}
+		break
+	}
+	return i
+}
+
+// GetStateChanges returns the state changes recorded in the item's LOGBOOK, newest first,
+// reading their times as wall clock times in loc
+func GetStateChanges(item *model.Item, loc *time.Location) []model.StateChange {
+	var changes []model.StateChange
+	inLogbook := false
+	var current *model.StateChange
+
+	flush := func() {
+		if current != nil {
+			current.Note = strings.TrimSpace(current.Note)
+			changes = append(changes, *current)
+			current = nil
+		}
+	}
+
+	for _, note := range item.Notes {
+		if logbookDrawerStart.MatchString(note) {
+			inLogbook = true
+			continue
+		}
+		if !inLogbook {
+			continue
+		}
+		if drawerEnd.MatchString(note) {
+			flush()
+			inLogbook = false
+			continue
+		}
+
+		if matches := stateChangePattern.FindStringSubmatch(note); matches != nil {
+			flush()
+			if t, err := parseClockTimestamp(matches[3], loc); err == nil {
+				current = &model.StateChange{
+					To:   model.TodoState(matches[1]),
+					From: model.TodoState(matches[2]),
+					Time: t,
+				}
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(note)
+		if current != nil && trimmed != "" && !strings.HasPrefix(trimmed, "- ") && !strings.HasPrefix(trimmed, "CLOCK:") {
+			// Indented continuation lines hold the note for the previous entry
+			if current.Note != "" {
+				current.Note += "\n"
+			}
+			current.Note += trimmed
+			continue
+		}
+		flush()
+	}
+	flush()
+
+	return changes
+}
+
+// clockEntryStarted returns true if a clock entry with the given start time exists in entries
+func clockEntryStarted(entries []model.ClockEntry, start time.Time) bool {
+	for _, entry := range entries {
+		if entry.Start.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateClockLines drops the CLOCK lines in the item's LOGBOOK drawer that no longer
+// match one of its clock entries, so that the writer writes the entries afresh. A drawer
+// left empty is removed unless the writer has entries to put in it.
+func UpdateClockLines(item *model.Item) {
+	loc := time.Local
+	if len(item.ClockEntries) > 0 {
+		loc = item.ClockEntries[0].Start.Location()
+	}
+
+	var updated []string
+	inLogbook := false
+	drawerStart := -1
+	for _, note := range item.Notes {
+		if logbookDrawerStart.MatchString(note) {
+			inLogbook = true
+			drawerStart = len(updated)
+		} else if inLogbook && drawerEnd.MatchString(note) {
+			inLogbook = false
+			if drawerStart == len(updated)-1 && len(item.ClockEntries) == 0 {
+				updated = updated[:drawerStart]
+				continue
+			}
+		} else if inLogbook {
+			if matches := clockPattern.FindStringSubmatch(note); matches != nil && !clockLineMatches(item.ClockEntries, matches, loc) {
+				continue
+			}
+		}
+		updated = append(updated, note)
+	}
+	item.Notes = updated
+}
+
+// clockLineMatches returns true if the start and end of a matched CLOCK line are those
+// of one of the entries
+func clockLineMatches(entries []model.ClockEntry, matches []string, loc *time.Location) bool {
+	start, err := parseClockTimestamp(matches[1], loc)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.Start.Equal(start) {
+			continue
+		}
+		if matches[2] == "" {
+			// The writer closes open lines once their entry has ended
+			return true
+		}
+		if entry.End == nil {
+			return false
+		}
+		end, err := parseClockTimestamp(matches[2], loc)
+		return err == nil && entry.End.Equal(end)
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// StripMetadata removes LOGBOOK and PROPERTIES drawer content and planning lines from notes,
+// leaving only the text written by the user
+func StripMetadata(notes []string) []string {
+	var filtered []string
+	inLogbook := false
+	inProperties := false
+
+	for _, note := range notes {
+		trimmed := strings.TrimSpace(note)
+
+		// Check for start of LOGBOOK drawer
+		if trimmed == ":LOGBOOK:" {
+			inLogbook = true
+			continue
+		}
+
+		// Check for start of PROPERTIES drawer
+		if trimmed == ":PROPERTIES:" {
+			inProperties = true
+			continue
+		}
+
+		// Check for end of drawer
+		if trimmed == ":END:" {
+			if inLogbook {
+				inLogbook = false
+				continue
+			}
+			if inProperties {
+				inProperties = false
+				continue
+			}
+		}
+
+		// Skip lines inside LOGBOOK or PROPERTIES drawer
+		if inLogbook || inProperties {
+			continue
+		}
+
+		// Skip SCHEDULED, DEADLINE, and CLOSED lines
+		if strings.HasPrefix(trimmed, "SCHEDULED:") || strings.HasPrefix(trimmed, "DEADLINE:") || strings.HasPrefix(trimmed, "CLOSED:") {
+			continue
+		}
+
+		filtered = append(filtered, note)
+	}
+
+	return filtered
+}
+
+// UpdateProperties rewrites the PROPERTIES drawer in the item's notes to match its
+// properties and effort, removing the ones that were cleared. Like UpdatePlanning, it
+// leaves notes without a drawer to the writer.
+func UpdateProperties(item *model.Item) {
+	inProperties := false
+	var noted []string
+	for _, note := range item.Notes {
+		if propertiesDrawerStart.MatchString(note) {
+			inProperties = true
+			continue
+		}
+		if inProperties && drawerEnd.MatchString(note) {
+			break
+		}
+		if inProperties {
+			if matches := propertyPattern.FindStringSubmatch(note); matches != nil {
+				noted = append(noted, matches[1])
+			}
+		}
+	}
+
+	// SetProperty keeps the drawer in sync, so setting every property to its current
+	// value updates changed lines, drops cleared ones and adds new ones
+	for _, name := range noted {
+		item.SetProperty(name, item.GetProperty(name))
+	}
+	names := make([]string, 0, len(item.Properties))
+	for name := range item.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		item.SetProperty(name, item.Properties[name])
+	}
+	item.SetProperty("EFFORT", item.Effort)
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bufio"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -14,11 +15,12 @@ import (
 
 // Parser patterns
 var (
-	scheduledPattern      = regexp.MustCompile(`SCHEDULED:\s*<([^>]+)>`)
-	deadlinePattern       = regexp.MustCompile(`DEADLINE:\s*<([^>]+)>`)
+	scheduledPattern      = regexp.MustCompile(`SCHEDULED:\s*<([^>]+)>(?:--<([^>]+)>)?`)
+	deadlinePattern       = regexp.MustCompile(`DEADLINE:\s*<([^>]+)>(?:--<([^>]+)>)?`)
 	closedPattern         = regexp.MustCompile(`CLOSED:\s*\[([^\]]+)\]`)
 	clockPattern          = regexp.MustCompile(`CLOCK:\s*\[([^\]]+)\](?:--\[([^\]]+)\])?`)
 	effortPattern         = regexp.MustCompile(`^\s*:EFFORT:\s*(.+)$`)
+	propertyPattern       = regexp.MustCompile(`^\s*:([^:\s]+):\s*(.*)$`)
 	logbookDrawerStart    = regexp.MustCompile(`^\s*:LOGBOOK:\s*$`)
 	propertiesDrawerStart = regexp.MustCompile(`^\s*:PROPERTIES:\s*$`)
 	drawerEnd             = regexp.MustCompile(`^\s*:END:\s*$`)
@@ -48,7 +50,6 @@ func buildHeadingPattern(cfg *config.Config) *regexp.Regexp {
 
 // ParseOrgFile reads and parses an org-mode file
 func ParseOrgFile(path string, cfg *config.Config) (*model.OrgFile, error) {
-	headingPattern := buildHeadingPattern(cfg)
 	file, err := os.Open(path)
 	if err != nil {
 		// If file doesn't exist, return empty org file
@@ -59,8 +60,20 @@ func ParseOrgFile(path string, cfg *config.Config) (*model.OrgFile, error) {
 	}
 	defer file.Close()
 
+	// Wait for a writer that is saving the file to finish
+	if err := lockFile(file, false); err != nil {
+		return nil, err
+	}
+
+	return Parse(file, path, cfg)
+}
+
+// Parse parses org-mode content read from r. path is recorded as the file's path.
+func Parse(r io.Reader, path string, cfg *config.Config) (*model.OrgFile, error) {
+	headingPattern := buildHeadingPattern(cfg)
+	loc := cfg.Zone()
 	orgFile := &model.OrgFile{Path: path, Items: []*model.Item{}}
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 
 	var currentItem *model.Item
 	var itemStack []*model.Item // Stack to track parent items
@@ -176,21 +189,29 @@ func ParseOrgFile(path string, cfg *config.Config) (*model.OrgFile, error) {
 
 			// Check for SCHEDULED
 			if matches := scheduledPattern.FindStringSubmatch(line); matches != nil {
-				if t, err := parseOrgDate(matches[1]); err == nil {
-					currentItem.Scheduled = &t
+				if ts, err := parsePlanningTimestamp(matches[1], matches[2], loc); err == nil {
+					currentItem.Scheduled = &ts.Start
+					currentItem.ScheduledEnd = ts.End
+					currentItem.ScheduledRepeater = ts.Repeater
+					currentItem.ScheduledDelay = ts.Warning
+					currentItem.ScheduledHasTime = ts.HasTime
 				}
 			}
 
 			// Check for DEADLINE
 			if matches := deadlinePattern.FindStringSubmatch(line); matches != nil {
-				if t, err := parseOrgDate(matches[1]); err == nil {
-					currentItem.Deadline = &t
+				if ts, err := parsePlanningTimestamp(matches[1], matches[2], loc); err == nil {
+					currentItem.Deadline = &ts.Start
+					currentItem.DeadlineEnd = ts.End
+					currentItem.DeadlineRepeater = ts.Repeater
+					currentItem.DeadlineWarning = ts.Warning
+					currentItem.DeadlineHasTime = ts.HasTime
 				}
 			}
 
 			// Check for CLOSED
 			if matches := closedPattern.FindStringSubmatch(line); matches != nil {
-				if t, err := parseClockTimestamp(matches[1]); err == nil {
+				if t, err := parseClockTimestamp(matches[1], loc); err == nil {
 					currentItem.Closed = &t
 				}
 			}
@@ -198,14 +219,22 @@ func ParseOrgFile(path string, cfg *config.Config) (*model.OrgFile, error) {
 			// Check for EFFORT (inside PROPERTIES drawer)
 			if matches := effortPattern.FindStringSubmatch(line); matches != nil {
 				currentItem.Effort = strings.TrimSpace(matches[1])
+			} else if inPropertiesDrawer {
+				// Any other property in the drawer (e.g. :ID:, :BLOCKER:, :ORDERED:)
+				if matches := propertyPattern.FindStringSubmatch(line); matches != nil {
+					if currentItem.Properties == nil {
+						currentItem.Properties = make(map[string]string)
+					}
+					currentItem.Properties[strings.ToUpper(matches[1])] = strings.TrimSpace(matches[2])
+				}
 			}
 
 			// Check for CLOCK (can be inside or outside drawer)
 			if matches := clockPattern.FindStringSubmatch(line); matches != nil {
-				if startTime, err := parseClockTimestamp(matches[1]); err == nil {
+				if startTime, err := parseClockTimestamp(matches[1], loc); err == nil {
 					entry := model.ClockEntry{Start: startTime}
 					if len(matches) > 2 && matches[2] != "" {
-						if endTime, err := parseClockTimestamp(matches[2]); err == nil {
+						if endTime, err := parseClockTimestamp(matches[2], loc); err == nil {
 							entry.End = &endTime
 						}
 					}
@@ -224,6 +253,9 @@ func ParseOrgFile(path string, cfg *config.Config) (*model.OrgFile, error) {
 		return nil, err
 	}
 
+	for _, item := range orgFile.AllItems() {
+		ScanTimestamps(item, loc)
+	}
 	return orgFile, nil
 }
 
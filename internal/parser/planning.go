@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// UpdatePlanning rewrites the SCHEDULED, DEADLINE and CLOSED timestamps in the item's
+// notes to match the model, removing them if the date was cleared. Planning info that
+// is not in the notes yet is written by the writer on save.
+func UpdatePlanning(item *model.Item) {
+	var updated []string
+	for _, note := range item.Notes {
+		line := note
+
+		if scheduledPattern.MatchString(line) {
+			replacement := ""
+			if item.Scheduled != nil {
+				replacement = "SCHEDULED: " + FormatPlanning(item.ScheduledTimestamp())
+			}
+			line = scheduledPattern.ReplaceAllLiteralString(line, replacement)
+		}
+
+		if deadlinePattern.MatchString(line) {
+			replacement := ""
+			if item.Deadline != nil {
+				replacement = "DEADLINE: " + FormatPlanning(item.DeadlineTimestamp())
+			}
+			line = deadlinePattern.ReplaceAllLiteralString(line, replacement)
+		}
+
+		if closedPattern.MatchString(line) {
+			replacement := ""
+			if item.Closed != nil {
+				replacement = "CLOSED: [" + formatClockTimestamp(*item.Closed) + "]"
+			}
+			line = closedPattern.ReplaceAllLiteralString(line, replacement)
+		}
+
+		if line != note {
+			// Drop planning lines that no longer hold anything, and tidy up spacing
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			indent := note[:len(note)-len(strings.TrimLeft(note, " \t"))]
+			line = indent + strings.Join(strings.Fields(line), " ")
+		}
+
+		updated = append(updated, line)
+	}
+	item.Notes = updated
+}
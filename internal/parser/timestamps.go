@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// activeTimestampPattern matches an active timestamp, or a range of two of them like
+// <2025-01-06 Mon>--<2025-01-08 Wed>
+var activeTimestampPattern = regexp.MustCompile(`<(\d{4}-\d{2}-\d{2}[^<>]*)>(?:--<(\d{4}-\d{2}-\d{2}[^<>]*)>)?`)
+
+// ScanTimestamps collects the plain active timestamps in the item's title and notes into
+// item.Timestamps, as wall clock times in loc. Planning lines, drawers and code blocks
+// are left out.
+func ScanTimestamps(item *model.Item, loc *time.Location) {
+	item.Timestamps = nil
+	lines := append([]string{item.Title}, StripMetadata(item.Notes)...)
+	inCodeBlock := false
+	for _, line := range lines {
+		if codeBlockStart.MatchString(line) {
+			inCodeBlock = true
+		} else if codeBlockEnd.MatchString(line) {
+			inCodeBlock = false
+		}
+		if inCodeBlock {
+			continue
+		}
+
+		for _, matches := range activeTimestampPattern.FindAllStringSubmatch(line, -1) {
+			if ts, err := parsePlanningTimestamp(matches[1], matches[2], loc); err == nil {
+				item.Timestamps = append(item.Timestamps, ts)
+			}
+		}
+	}
+}
@@ -3,8 +3,9 @@ package parser
 import (
 	"bufio"
 	"fmt"
-	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/rwejlgaard/org/internal/model"
 )
@@ -23,7 +24,7 @@ func Save(orgFile *model.OrgFile) error {
 	}
 
 	// Single file mode
-	file, err := os.Create(orgFile.Path)
+	file, err := createLocked(orgFile.Path)
 	if err != nil {
 		return err
 	}
@@ -67,7 +68,7 @@ func saveMultiFile(orgFile *model.OrgFile) error {
 
 // saveItemsToFile writes a list of items to a specific file
 func saveItemsToFile(filePath string, items []*model.Item) error {
-	file, err := os.Create(filePath)
+	file, err := createLocked(filePath)
 	if err != nil {
 		return err
 	}
@@ -157,27 +158,41 @@ func writeItem(writer *bufio.Writer, item *model.Item) error {
 	}
 
 	if item.Scheduled != nil && !hasScheduled {
-		scheduledLine := fmt.Sprintf("SCHEDULED: <%s>\n", FormatOrgDate(*item.Scheduled))
+		scheduledLine := fmt.Sprintf("SCHEDULED: %s\n", FormatPlanning(item.ScheduledTimestamp()))
 		if _, err := writer.WriteString(scheduledLine); err != nil {
 			return err
 		}
 	}
 
 	if item.Deadline != nil && !hasDeadline {
-		deadlineLine := fmt.Sprintf("DEADLINE: <%s>\n", FormatOrgDate(*item.Deadline))
+		deadlineLine := fmt.Sprintf("DEADLINE: %s\n", FormatPlanning(item.DeadlineTimestamp()))
 		if _, err := writer.WriteString(deadlineLine); err != nil {
 			return err
 		}
 	}
 
-	// Write effort in :PROPERTIES: drawer if not already in notes
-	if item.Effort != "" && !hasProperties {
+	// Write effort and other properties in :PROPERTIES: drawer if not already in notes
+	if (item.Effort != "" || len(item.Properties) > 0) && !hasProperties {
 		if _, err := writer.WriteString(":PROPERTIES:\n"); err != nil {
 			return err
 		}
-		effortLine := fmt.Sprintf(":EFFORT: %s\n", item.Effort)
-		if _, err := writer.WriteString(effortLine); err != nil {
-			return err
+		if item.Effort != "" {
+			effortLine := fmt.Sprintf(":EFFORT: %s\n", item.Effort)
+			if _, err := writer.WriteString(effortLine); err != nil {
+				return err
+			}
+		}
+		// Sort property names so output is stable between saves
+		names := make([]string, 0, len(item.Properties))
+		for name := range item.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propertyLine := fmt.Sprintf(":%s: %s\n", name, item.Properties[name])
+			if _, err := writer.WriteString(propertyLine); err != nil {
+				return err
+			}
 		}
 		if _, err := writer.WriteString(":END:\n"); err != nil {
 			return err
@@ -190,12 +205,7 @@ func writeItem(writer *bufio.Writer, item *model.Item) error {
 			return err
 		}
 		for _, entry := range item.ClockEntries {
-			clockLine := fmt.Sprintf("CLOCK: [%s]", formatClockTimestamp(entry.Start))
-			if entry.End != nil {
-				clockLine += fmt.Sprintf("--[%s]", formatClockTimestamp(*entry.End))
-			}
-			clockLine += "\n"
-			if _, err := writer.WriteString(clockLine); err != nil {
+			if _, err := writer.WriteString(formatClockLine(entry) + "\n"); err != nil {
 				return err
 			}
 		}
@@ -205,10 +215,8 @@ func writeItem(writer *bufio.Writer, item *model.Item) error {
 	}
 
 	// Write notes
-	for _, note := range item.Notes {
-		if _, err := writer.WriteString(note + "\n"); err != nil {
-			return err
-		}
+	if err := writeNotes(writer, item); err != nil {
+		return err
 	}
 
 	// Write children
@@ -220,3 +228,80 @@ func writeItem(writer *bufio.Writer, item *model.Item) error {
 
 	return nil
 }
+
+// formatClockLine formats a clock entry as an org CLOCK line
+func formatClockLine(entry model.ClockEntry) string {
+	clockLine := fmt.Sprintf("CLOCK: [%s]", formatClockTimestamp(entry.Start))
+	if entry.End != nil {
+		clockLine += fmt.Sprintf("--[%s]", formatClockTimestamp(*entry.End))
+	}
+	return clockLine
+}
+
+// writeNotes writes the item's notes, keeping CLOCK lines in an existing LOGBOOK
+// drawer in sync with the item's clock entries (new entries are added at the top,
+// and entries that have since been clocked out get their end time)
+func writeNotes(writer *bufio.Writer, item *model.Item) error {
+	// Collect the clock entries already recorded in the notes, reading them in the
+	// timezone the item's entries are in so that they can be matched up
+	loc := time.Local
+	if len(item.ClockEntries) > 0 {
+		loc = item.ClockEntries[0].Start.Location()
+	}
+	var notedEntries []model.ClockEntry
+	for _, note := range item.Notes {
+		if matches := clockPattern.FindStringSubmatch(note); matches != nil {
+			if startTime, err := parseClockTimestamp(matches[1], loc); err == nil {
+				notedEntries = append(notedEntries, model.ClockEntry{Start: startTime})
+			}
+		}
+	}
+
+	inLogbook := false
+	for _, note := range item.Notes {
+		line := note
+
+		if logbookDrawerStart.MatchString(note) {
+			inLogbook = true
+			if _, err := writer.WriteString(line + "\n"); err != nil {
+				return err
+			}
+			// Newest entries first, matching org's LOGBOOK order
+			for i := len(item.ClockEntries) - 1; i >= 0; i-- {
+				entry := item.ClockEntries[i]
+				if clockEntryStarted(notedEntries, entry.Start) {
+					continue
+				}
+				if _, err := writer.WriteString(formatClockLine(entry) + "\n"); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if inLogbook && drawerEnd.MatchString(note) {
+			inLogbook = false
+		}
+
+		// Close open clock lines that have since been clocked out
+		if inLogbook {
+			if matches := clockPattern.FindStringSubmatch(note); matches != nil && matches[2] == "" {
+				if startTime, err := parseClockTimestamp(matches[1], loc); err == nil {
+					for _, entry := range item.ClockEntries {
+						if entry.Start.Equal(startTime) && entry.End != nil {
+							indent := note[:len(note)-len(strings.TrimLeft(note, " \t"))]
+							line = indent + formatClockLine(entry)
+							break
+						}
+					}
+				}
+			}
+		}
+
+		if _, err := writer.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
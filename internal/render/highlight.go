@@ -0,0 +1,36 @@
+package render
+
+import (
+	"io"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// HighlightStyle is the chroma style used for code blocks everywhere
+const HighlightStyle = "monokai"
+
+// Highlight writes syntax highlighted code using the given chroma formatter
+// If the language is unknown it is guessed from the code
+func Highlight(w io.Writer, code, language string, formatter chroma.Formatter) error {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	style := styles.Get(HighlightStyle)
+	if style == nil {
+		style = styles.Fallback
+	}
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return err
+	}
+	return formatter.Format(w, style, iterator)
+}
@@ -1,9 +1,9 @@
-package ui
+package render
 
 import "strings"
 
-// renderLatexMath converts LaTeX math expressions to Unicode for terminal display
-func renderLatexMath(latex string) string {
+// RenderLatexMath converts LaTeX math expressions to Unicode for terminal display
+func RenderLatexMath(latex string) string {
 	result := latex
 
 	// Remove LaTeX math delimiters
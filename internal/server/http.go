@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Handler returns the REST API:
+//
+//	GET    /items                  list items, filtered by ?state= &tag= &priority= &q= &open=true
+//	POST   /items                  create an item from a NewItem
+//	GET    /items/{ref}            get one item
+//	PATCH  /items/{ref}            change fields given in an Update
+//	DELETE /items/{ref}            delete an item and its children
+//	POST   /items/{ref}/clock-in   start the clock
+//	POST   /items/{ref}/clock-out  stop the clock
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /items", func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		items, err := s.List(Query{
+			State:    params.Get("state"),
+			Tag:      params.Get("tag"),
+			Priority: params.Get("priority"),
+			Text:     params.Get("q"),
+			Open:     params.Get("open") == "true",
+		})
+		respond(w, http.StatusOK, items, err)
+	})
+
+	mux.HandleFunc("POST /items", func(w http.ResponseWriter, r *http.Request) {
+		var n NewItem
+		if err := decodeBody(r, &n); err != nil {
+			respond(w, 0, nil, err)
+			return
+		}
+		item, err := s.Create(n)
+		respond(w, http.StatusCreated, item, err)
+	})
+
+	mux.HandleFunc("GET /items/{ref}", func(w http.ResponseWriter, r *http.Request) {
+		item, err := s.Get(r.PathValue("ref"))
+		respond(w, http.StatusOK, item, err)
+	})
+
+	mux.HandleFunc("PATCH /items/{ref}", func(w http.ResponseWriter, r *http.Request) {
+		var u Update
+		if err := decodeBody(r, &u); err != nil {
+			respond(w, 0, nil, err)
+			return
+		}
+		item, err := s.Update(r.PathValue("ref"), u)
+		respond(w, http.StatusOK, item, err)
+	})
+
+	mux.HandleFunc("DELETE /items/{ref}", func(w http.ResponseWriter, r *http.Request) {
+		err := s.Delete(r.PathValue("ref"))
+		respond(w, http.StatusNoContent, nil, err)
+	})
+
+	mux.HandleFunc("POST /items/{ref}/clock-in", func(w http.ResponseWriter, r *http.Request) {
+		item, err := s.ClockIn(r.PathValue("ref"))
+		respond(w, http.StatusOK, item, err)
+	})
+
+	mux.HandleFunc("POST /items/{ref}/clock-out", func(w http.ResponseWriter, r *http.Request) {
+		item, err := s.ClockOut(r.PathValue("ref"))
+		respond(w, http.StatusOK, item, err)
+	})
+
+	return mux
+}
+
+// decodeBody reads a JSON request body, rejecting unknown fields so typos don't go unnoticed
+func decodeBody(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return errorf(http.StatusBadRequest, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+// respond writes the result as JSON, or the error as {"error": "..."} with its status
+func respond(w http.ResponseWriter, status int, result interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err != nil {
+		var apiErr *Error
+		if !errors.As(err, &apiErr) {
+			log.Printf("error: %v", err)
+			apiErr = &Error{Status: http.StatusInternalServerError, Message: err.Error()}
+		}
+		w.WriteHeader(apiErr.Status)
+		json.NewEncoder(w).Encode(map[string]string{"error": apiErr.Message})
+		return
+	}
+
+	w.WriteHeader(status)
+	if status != http.StatusNoContent {
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(result)
+	}
+}
+
+// ListenAndServe serves the API on addr until the server fails
+func (s *Server) ListenAndServe(addr string) error {
+	if !strings.HasPrefix(addr, "127.") && !strings.HasPrefix(addr, "localhost:") && !strings.HasPrefix(addr, "[::1]:") {
+		log.Printf("warning: %s is reachable from other machines and the API has no authentication", addr)
+	}
+	return http.ListenAndServe(addr, s.Handler())
+}
@@ -0,0 +1,322 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, or a notification if it has no ID
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error member of a response. Errors from the server carry their HTTP
+// status in data, so clients can tell a missing item from a refused change.
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// rpcNotification is a message sent to the client without a request
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// refParams are the parameters of methods that act on a single item
+type refParams struct {
+	Ref string `json:"ref"`
+}
+
+// RPC speaks JSON-RPC 2.0 with one message per line. The org file is loaded once and kept
+// in memory; it is reloaded when a file changes on disk, and the client is sent a
+// "changed" notification whenever the tree changes.
+type RPC struct {
+	server   *Server
+	cache    *fileCache
+	out      *json.Encoder
+	outMu    sync.Mutex
+	interval time.Duration
+}
+
+// NewRPC creates a JSON-RPC endpoint for the org file (or directory) returned by load
+func NewRPC(load func() (*model.OrgFile, error), cfg *config.Config) *RPC {
+	cache := &fileCache{load: load}
+	r := &RPC{server: newServer(cache.get, cfg), cache: cache, interval: time.Second}
+	r.server.OnSave(func(orgFile *model.OrgFile) {
+		// Our own changes don't need a reload
+		cache.stamp()
+		r.notify("changed", map[string]string{"reason": "edit"})
+	})
+	r.server.discard = cache.invalidate
+	cache.onReload = func() {
+		r.notify("changed", map[string]string{"reason": "disk"})
+	}
+	return r
+}
+
+// Serve reads requests from in and writes responses and notifications to out until in is closed
+func (r *RPC) Serve(in io.Reader, out io.Writer) error {
+	r.out = json.NewEncoder(out)
+	r.out.SetEscapeHTML(false)
+
+	// Watch the files for changes made by other programs
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				r.server.view(func(*model.OrgFile) error { return nil })
+			}
+		}
+	}()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			r.send(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			r.send(rpcResponse{JSONRPC: "2.0", ID: idOrNull(req.ID), Error: &rpcError{Code: rpcInvalidRequest, Message: "invalid request"}})
+			continue
+		}
+
+		result, rpcErr := r.call(req.Method, req.Params)
+		if len(req.ID) == 0 {
+			// Notifications get no response
+			continue
+		}
+		r.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+	}
+	return scanner.Err()
+}
+
+// call runs a method, mirroring the actions available in the TUI
+func (r *RPC) call(method string, params json.RawMessage) (interface{}, *rpcError) {
+	var result interface{}
+	var err error
+
+	switch method {
+	case "search":
+		var p Query
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		result, err = r.server.List(p)
+	case "get":
+		var p refParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		result, err = r.server.Get(p.Ref)
+	case "capture":
+		var p NewItem
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		result, err = r.server.Create(p)
+	case "update":
+		var p struct {
+			Ref string `json:"ref"`
+			Update
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		result, err = r.server.Update(p.Ref, p.Update)
+	case "cycleState":
+		var p struct {
+			Ref      string `json:"ref"`
+			Backward bool   `json:"backward"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		result, err = r.server.CycleState(p.Ref, p.Backward)
+	case "setScheduled", "setDeadline":
+		var p struct {
+			Ref  string `json:"ref"`
+			Date string `json:"date"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		u := Update{Scheduled: &p.Date}
+		if method == "setDeadline" {
+			u = Update{Deadline: &p.Date}
+		}
+		result, err = r.server.Update(p.Ref, u)
+	case "clockIn", "clockOut":
+		var p refParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if method == "clockIn" {
+			result, err = r.server.ClockIn(p.Ref)
+		} else {
+			result, err = r.server.ClockOut(p.Ref)
+		}
+	case "refile":
+		var p struct {
+			Ref    string `json:"ref"`
+			Target string `json:"target"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		result, err = r.server.Refile(p.Ref, p.Target)
+	case "delete":
+		var p refParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		err = r.server.Delete(p.Ref)
+		result = true
+	default:
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "unknown method " + method}
+	}
+
+	if err != nil {
+		var apiErr *Error
+		if errors.As(err, &apiErr) {
+			return nil, &rpcError{Code: rpcServerError, Message: apiErr.Message, Data: map[string]int{"status": apiErr.Status}}
+		}
+		return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// send writes a message, keeping responses and notifications from interleaving
+func (r *RPC) send(message interface{}) {
+	r.outMu.Lock()
+	defer r.outMu.Unlock()
+	r.out.Encode(message)
+}
+
+// notify sends a notification to the client
+func (r *RPC) notify(method string, params interface{}) {
+	if r.out != nil {
+		r.send(rpcNotification{JSONRPC: "2.0", Method: method, Params: params})
+	}
+}
+
+// decodeParams reads a method's parameters
+func decodeParams(params json.RawMessage, v interface{}) *rpcError {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// idOrNull returns the request ID, or null if the request had none
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
+
+// fileCache keeps an org file in memory and reloads it when one of its files changes
+type fileCache struct {
+	load     func() (*model.OrgFile, error)
+	orgFile  *model.OrgFile
+	stamps   map[string]fileStamp
+	onReload func()
+}
+
+// get returns the org file, reloading it if it changed on disk since it was last seen,
+// and the stamps its files had when it was read
+func (c *fileCache) get() (*model.OrgFile, map[string]fileStamp, error) {
+	var stamps map[string]fileStamp
+	if c.orgFile != nil {
+		// Stamp before reading, so that a change made while reading is noticed later
+		stamps = fileStamps(c.orgFile)
+		if sameStamps(stamps, c.stamps) {
+			return c.orgFile, c.stamps, nil
+		}
+	}
+
+	orgFile, err := c.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	reloaded := c.orgFile != nil
+	c.orgFile = orgFile
+	if stamps == nil {
+		// The files aren't known until the first load
+		stamps = fileStamps(orgFile)
+	}
+	c.stamps = stamps
+	if reloaded && c.onReload != nil {
+		c.onReload()
+	}
+	return orgFile, stamps, nil
+}
+
+// invalidate drops the file from memory, so that it is read again on the next call
+func (c *fileCache) invalidate() {
+	c.orgFile = nil
+}
+
+// stamp records the modification times and sizes of the files as they are now
+func (c *fileCache) stamp() {
+	c.stamps = fileStamps(c.orgFile)
+}
+
+// sameStamps reports whether no file was changed, added or removed between two stamps
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, stamp := range a {
+		if other, ok := b[path]; !ok || !other.equal(stamp) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,814 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/gitsync"
+	"github.com/rwejlgaard/org/internal/hooks"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// Server gives programs access to the items of an org file. The file on disk stays the
+// source of truth: every call asks load for its current contents, and calls that change
+// something save it again through parser.Save, which locks the file while writing.
+type Server struct {
+	load    stampedLoad
+	config  *config.Config
+	mu      sync.Mutex // Serializes load-modify-save cycles within this process
+	onSave  []func(orgFile *model.OrgFile)
+	discard func()              // Called when a change fails halfway, to drop a cached copy of the file
+	hooks   []*hooks.Invocation // Hooks for the change in progress, run once it is saved
+}
+
+// stampedLoad loads the org file along with the stamps its files had when they were read,
+// which a change is checked against before it is saved
+type stampedLoad func() (*model.OrgFile, map[string]fileStamp, error)
+
+// New creates a server around a function that loads the org file (or directory)
+func New(load func() (*model.OrgFile, error), cfg *config.Config) *Server {
+	return newServer(func() (*model.OrgFile, map[string]fileStamp, error) {
+		orgFile, err := load()
+		if err != nil {
+			return nil, nil, err
+		}
+		return orgFile, fileStamps(orgFile), nil
+	}, cfg)
+}
+
+func newServer(load stampedLoad, cfg *config.Config) *Server {
+	return &Server{load: load, config: cfg}
+}
+
+// Error is an error with the HTTP status it should be reported with
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// errorf creates an Error with a formatted message
+func errorf(status int, format string, args ...interface{}) *Error {
+	return &Error{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// Item is the representation of a heading returned by the API
+type Item struct {
+	Ref          string            `json:"ref"`
+	Path         []string          `json:"path"`
+	Level        int               `json:"level"`
+	State        string            `json:"state,omitempty"`
+	Priority     string            `json:"priority,omitempty"`
+	Title        string            `json:"title"`
+	Tags         []string          `json:"tags"`
+	Scheduled    *time.Time        `json:"scheduled,omitempty"`
+	ScheduledEnd *time.Time        `json:"scheduled_end,omitempty"`
+	Deadline     *time.Time        `json:"deadline,omitempty"`
+	DeadlineEnd  *time.Time        `json:"deadline_end,omitempty"`
+	Closed       *time.Time        `json:"closed,omitempty"`
+	Effort       string            `json:"effort,omitempty"`
+	Properties   map[string]string `json:"properties,omitempty"`
+	Notes        []string          `json:"notes,omitempty"`
+	ClockedIn    bool              `json:"clocked_in"`
+	ClockMinutes int               `json:"clock_minutes"`
+	SourceFile   string            `json:"source_file,omitempty"`
+	Children     []string          `json:"children,omitempty"`
+}
+
+// Query selects items to list. Empty fields match everything.
+type Query struct {
+	State    string `json:"state,omitempty"` // Exact state, "none" for items without one
+	Tag      string `json:"tag,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	Text     string `json:"text,omitempty"` // Case-insensitive substring of the title or notes
+	Open     bool   `json:"open,omitempty"` // Only items with a state that isn't done
+}
+
+// NewItem describes an item to create
+type NewItem struct {
+	Parent    string   `json:"parent,omitempty"` // Ref of the parent, empty for a top-level item
+	Title     string   `json:"title"`
+	State     *string  `json:"state,omitempty"` // Default new task state if not given
+	Priority  string   `json:"priority,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Scheduled string   `json:"scheduled,omitempty"`
+	Deadline  string   `json:"deadline,omitempty"`
+	Notes     []string `json:"notes,omitempty"`
+}
+
+// Update lists the fields to change on an item. Nil fields are left alone, and empty
+// dates clear the date.
+type Update struct {
+	Title     *string   `json:"title,omitempty"`
+	State     *string   `json:"state,omitempty"`
+	Priority  *string   `json:"priority,omitempty"`
+	Tags      *[]string `json:"tags,omitempty"`
+	Scheduled *string   `json:"scheduled,omitempty"`
+	Deadline  *string   `json:"deadline,omitempty"`
+	Effort    *string   `json:"effort,omitempty"`
+}
+
+// view runs fn against a freshly loaded org file
+func (s *Server) view(fn func(orgFile *model.OrgFile) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orgFile, _, err := s.load()
+	if err != nil {
+		return err
+	}
+	return fn(orgFile)
+}
+
+// modify runs fn against a freshly loaded org file and saves it if fn succeeds. The save
+// is refused with a conflict if a file was changed on disk since it was loaded, so that
+// edits made by other programs in the meantime aren't overwritten.
+func (s *Server) modify(fn func(orgFile *model.OrgFile) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orgFile, loaded, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := s.apply(orgFile, loaded, fn); err != nil {
+		// The loaded copy may have been changed, so it can't be used again
+		if s.discard != nil {
+			s.discard()
+		}
+		return err
+	}
+	if err := gitsync.CommitSaved(orgFile, s.config); err != nil {
+		log.Printf("Changes saved but not committed: %v", err)
+	}
+	for _, fn := range s.onSave {
+		fn(orgFile)
+	}
+
+	s.queueHook(orgFile, hooks.Saved, nil, nil)
+	pending := s.hooks
+	go func() {
+		for _, inv := range pending {
+			if err := inv.Run(); err != nil {
+				log.Print(err)
+			}
+		}
+	}()
+	return nil
+}
+
+// apply runs fn against the org file and saves it, unless a file changed on disk since it
+// was loaded with the given stamps
+func (s *Server) apply(orgFile *model.OrgFile, loaded map[string]fileStamp, fn func(orgFile *model.OrgFile) error) error {
+	s.hooks = nil
+	if err := fn(orgFile); err != nil {
+		return err
+	}
+	current := fileStamps(orgFile)
+	for path, stamp := range loaded {
+		if now, ok := current[path]; !ok || !now.equal(stamp) {
+			return errorf(http.StatusConflict, "%s was changed on disk, try again", filepath.Base(path))
+		}
+	}
+	return parser.Save(orgFile)
+}
+
+// queueHook prepares the hook for an event, if one is configured, to run after the save
+func (s *Server) queueHook(orgFile *model.OrgFile, event string, item *model.Item, extra map[string]string) {
+	file := orgFile.Path
+	if item != nil && item.SourceFile != "" {
+		file = item.SourceFile
+	}
+	if inv := hooks.Prepare(s.config, event, item, file, extra); inv != nil {
+		s.hooks = append(s.hooks, inv)
+	}
+}
+
+// OnSave registers a function to call after every change has been saved
+func (s *Server) OnSave(fn func(orgFile *model.OrgFile)) {
+	s.onSave = append(s.onSave, fn)
+}
+
+// fileStamp is the modification time and size of a file, used to notice changes made by
+// other programs
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (f fileStamp) equal(other fileStamp) bool {
+	return f.modTime.Equal(other.modTime) && f.size == other.size
+}
+
+// fileStamps returns the stamps of the files behind an org file: the file itself, or
+// every org file in the directory in multi-file mode
+func fileStamps(orgFile *model.OrgFile) map[string]fileStamp {
+	paths := []string{orgFile.Path}
+	if info, err := os.Stat(orgFile.Path); err == nil && info.IsDir() {
+		paths, _ = filepath.Glob(filepath.Join(orgFile.Path, "*.org"))
+	}
+
+	stamps := make(map[string]fileStamp)
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+// List returns the items matching the query, in outline order
+func (s *Server) List(q Query) ([]Item, error) {
+	items := []Item{}
+	err := s.view(func(orgFile *model.OrgFile) error {
+		walkItems(orgFile.Items, "", nil, func(item *model.Item, ref string, path []string) {
+			if s.matches(item, q) {
+				items = append(items, s.toAPI(orgFile, item, ref, path))
+			}
+		})
+		return nil
+	})
+	return items, err
+}
+
+// Get returns a single item
+func (s *Server) Get(ref string) (Item, error) {
+	var result Item
+	err := s.view(func(orgFile *model.OrgFile) error {
+		item, err := s.find(orgFile, ref)
+		if err != nil {
+			return err
+		}
+		result = s.itemResult(orgFile, item)
+		return nil
+	})
+	return result, err
+}
+
+// Create adds a new item as the last child of its parent, or at the end of the file
+func (s *Server) Create(n NewItem) (Item, error) {
+	var result Item
+	err := s.modify(func(orgFile *model.OrgFile) error {
+		title := strings.Join(strings.Fields(n.Title), " ")
+		if title == "" {
+			return errorf(http.StatusBadRequest, "title is required")
+		}
+
+		item := &model.Item{Level: 1, Title: title, Tags: n.Tags}
+		for _, note := range n.Notes {
+			// Keep notes from being read as headings
+			if strings.HasPrefix(note, "*") {
+				note = " " + note
+			}
+			item.Notes = append(item.Notes, note)
+		}
+		parser.ScanTimestamps(item, s.config.Zone())
+
+		state := s.config.GetDefaultNewTaskState()
+		if n.State != nil {
+			state = *n.State
+		}
+		if err := s.validateState(state); err != nil {
+			return err
+		}
+		item.State = model.TodoState(state)
+
+		if err := setPriority(item, n.Priority); err != nil {
+			return err
+		}
+		var err error
+		if item.Scheduled, item.ScheduledEnd, item.ScheduledHasTime, err = parseDate(n.Scheduled, s.config.Zone()); err != nil {
+			return err
+		}
+		if item.Deadline, item.DeadlineEnd, item.DeadlineHasTime, err = parseDate(n.Deadline, s.config.Zone()); err != nil {
+			return err
+		}
+
+		if n.Parent != "" {
+			parent, err := s.find(orgFile, n.Parent)
+			if err != nil {
+				return err
+			}
+			item.Level = parent.Level + 1
+			item.SourceFile = parent.SourceFile
+			parent.Children = append(parent.Children, item)
+		} else if isMultiFile(orgFile) {
+			// Top-level items stand for the files themselves
+			return errorf(http.StatusBadRequest, "parent is required when serving a directory")
+		} else {
+			orgFile.Items = append(orgFile.Items, item)
+		}
+
+		s.queueHook(orgFile, hooks.Captured, item, nil)
+		result = s.itemResult(orgFile, item)
+		return nil
+	})
+	return result, err
+}
+
+// Update changes the given fields of an item. State changes close, reopen and repeat
+// items the same way the TUI does.
+func (s *Server) Update(ref string, u Update) (Item, error) {
+	var result Item
+	err := s.modify(func(orgFile *model.OrgFile) error {
+		item, err := s.find(orgFile, ref)
+		if err != nil {
+			return err
+		}
+
+		if u.Title != nil {
+			title := strings.Join(strings.Fields(*u.Title), " ")
+			if title == "" {
+				return errorf(http.StatusBadRequest, "title can't be empty")
+			}
+			item.Title = title
+			parser.ScanTimestamps(item, s.config.Zone())
+		}
+		if u.Priority != nil {
+			if err := setPriority(item, *u.Priority); err != nil {
+				return err
+			}
+		}
+		if u.Tags != nil {
+			item.Tags = *u.Tags
+		}
+		if u.Effort != nil {
+			item.SetProperty("EFFORT", *u.Effort)
+		}
+		if u.Scheduled != nil {
+			if item.Scheduled, item.ScheduledEnd, item.ScheduledHasTime, err = parseDate(*u.Scheduled, s.config.Zone()); err != nil {
+				return err
+			}
+			if item.Scheduled == nil {
+				item.ScheduledRepeater = ""
+				item.ScheduledDelay = ""
+			}
+		}
+		if u.Deadline != nil {
+			if item.Deadline, item.DeadlineEnd, item.DeadlineHasTime, err = parseDate(*u.Deadline, s.config.Zone()); err != nil {
+				return err
+			}
+			if item.Deadline == nil {
+				item.DeadlineRepeater = ""
+				item.DeadlineWarning = ""
+			}
+		}
+		parser.UpdatePlanning(item)
+		if u.Deadline != nil && item.Deadline != nil {
+			s.queueHook(orgFile, hooks.DeadlineSet, item, nil)
+		}
+
+		if u.State != nil && *u.State != string(item.State) {
+			if err := s.validateState(*u.State); err != nil {
+				return err
+			}
+			if err := s.changeState(orgFile, item, *u.State); err != nil {
+				return err
+			}
+		}
+
+		result = s.itemResult(orgFile, item)
+		return nil
+	})
+	return result, err
+}
+
+// ClockIn starts the clock on an item
+func (s *Server) ClockIn(ref string) (Item, error) {
+	return s.clock(ref, (*model.Item).ClockIn, hooks.ClockIn, "already clocked in")
+}
+
+// ClockOut stops the clock on an item
+func (s *Server) ClockOut(ref string) (Item, error) {
+	return s.clock(ref, (*model.Item).ClockOut, hooks.ClockOut, "not clocked in")
+}
+
+// clock applies a clock change, failing with a conflict if it didn't apply
+func (s *Server) clock(ref string, change func(*model.Item, time.Time) bool, event, conflict string) (Item, error) {
+	var result Item
+	err := s.modify(func(orgFile *model.OrgFile) error {
+		item, err := s.find(orgFile, ref)
+		if err != nil {
+			return err
+		}
+		if !change(item, s.config.Now()) {
+			return errorf(http.StatusConflict, "%s", conflict)
+		}
+		s.queueHook(orgFile, event, item, nil)
+		result = s.itemResult(orgFile, item)
+		return nil
+	})
+	return result, err
+}
+
+// CycleState moves an item to the next configured state, or the previous one if backward
+// is set, passing through the empty state like the TUI does
+func (s *Server) CycleState(ref string, backward bool) (Item, error) {
+	var result Item
+	err := s.modify(func(orgFile *model.OrgFile) error {
+		item, err := s.find(orgFile, ref)
+		if err != nil {
+			return err
+		}
+
+		// The empty state sits between the last state and the first
+		states := append([]string{""}, s.config.GetStateNames()...)
+		current := 0
+		for i, state := range states {
+			if state == string(item.State) {
+				current = i
+			}
+		}
+		next := (current + 1) % len(states)
+		if backward {
+			next = (current + len(states) - 1) % len(states)
+		}
+
+		if err := s.changeState(orgFile, item, states[next]); err != nil {
+			return err
+		}
+		result = s.itemResult(orgFile, item)
+		return nil
+	})
+	return result, err
+}
+
+// Refile moves an item and its children to the end of the target's children, or to the
+// end of the file if target is empty
+func (s *Server) Refile(ref, target string) (Item, error) {
+	var result Item
+	err := s.modify(func(orgFile *model.OrgFile) error {
+		item, err := s.find(orgFile, ref)
+		if err != nil {
+			return err
+		}
+		if isMultiFile(orgFile) && item.Level == 1 {
+			return errorf(http.StatusBadRequest, "can't refile a file")
+		}
+
+		level := 1
+		sourceFile := ""
+		var parent *model.Item
+		if target != "" {
+			if parent, err = s.find(orgFile, target); err != nil {
+				return err
+			}
+			for p := parent; p != nil; p = orgFile.FindParent(p) {
+				if p == item {
+					return errorf(http.StatusBadRequest, "can't refile an item below itself")
+				}
+			}
+			level = parent.Level + 1
+			sourceFile = parent.SourceFile
+		} else if isMultiFile(orgFile) {
+			return errorf(http.StatusBadRequest, "target is required when serving a directory")
+		}
+
+		orgFile.Items = removeItem(orgFile.Items, item)
+		moveItem(item, level-item.Level, sourceFile)
+		if parent != nil {
+			parent.Children = append(parent.Children, item)
+		} else {
+			orgFile.Items = append(orgFile.Items, item)
+		}
+
+		result = s.itemResult(orgFile, item)
+		return nil
+	})
+	return result, err
+}
+
+// Delete removes an item and its children
+func (s *Server) Delete(ref string) error {
+	return s.modify(func(orgFile *model.OrgFile) error {
+		item, err := s.find(orgFile, ref)
+		if err != nil {
+			return err
+		}
+		if isMultiFile(orgFile) && item.Level == 1 {
+			return errorf(http.StatusBadRequest, "can't delete a file")
+		}
+		s.queueHook(orgFile, hooks.Deleted, item, nil)
+		orgFile.Items = removeItem(orgFile.Items, item)
+		return nil
+	})
+}
+
+// changeState moves an item to a new state: completing it is refused while it has open
+// blockers, the clock is stopped, repeating items are rescheduled and reopened, and the
+// CLOSED timestamp and LOGBOOK are kept up to date
+func (s *Server) changeState(orgFile *model.OrgFile, item *model.Item, newState string) error {
+	oldState := string(item.State)
+	wasDone := s.config.IsDoneState(oldState)
+	isDone := s.config.IsDoneState(newState)
+
+	if isDone {
+		isDoneState := func(state model.TodoState) bool {
+			return s.config.IsDoneState(string(state))
+		}
+		if blockers := orgFile.OpenBlockers(item, isDoneState); len(blockers) > 0 {
+			names := make([]string, len(blockers))
+			for i, blocker := range blockers {
+				names[i] = blocker.Title
+			}
+			return errorf(http.StatusConflict, "blocked by: %s", strings.Join(names, ", "))
+		}
+		if item.IsClockedIn() {
+			item.ClockOut(s.config.Now())
+			s.queueHook(orgFile, hooks.ClockOut, item, nil)
+		}
+	}
+
+	// Hooks see the item once the change is complete
+	defer func() {
+		s.queueHook(orgFile, hooks.StateChanged, item, map[string]string{"from": oldState, "to": newState})
+		// Completing a repeating task reopens it, which hooks see as a second change
+		if finalState := string(item.State); finalState != newState {
+			s.queueHook(orgFile, hooks.StateChanged, item, map[string]string{"from": newState, "to": finalState})
+		}
+	}()
+
+	now := s.config.Now()
+	change := model.StateChange{From: model.TodoState(oldState), To: model.TodoState(newState), Time: now}
+	item.State = model.TodoState(newState)
+
+	if isDone && !wasDone && item.IsRepeating() {
+		// Repeated completions are always logged, since the LOGBOOK is their only record
+		parser.LogStateChange(item, change)
+		item.AdvanceRepeats(now)
+		parser.UpdatePlanning(item)
+		item.SetProperty("LAST_REPEAT", "["+parser.FormatOrgDateTime(now)+"]")
+
+		reopenState := item.GetProperty("REPEAT_TO_STATE")
+		if reopenState == "" {
+			reopenState = s.config.GetStateNames()[0]
+		}
+		item.State = model.TodoState(reopenState)
+		return nil
+	}
+
+	if logChange, _ := s.config.GetStateLogging(newState); logChange {
+		parser.LogStateChange(item, change)
+	}
+
+	if isDone && !wasDone {
+		item.Closed = &now
+	} else if wasDone && !isDone {
+		item.Closed = nil
+	}
+	parser.UpdatePlanning(item)
+	return nil
+}
+
+// matches reports whether an item is selected by the query
+func (s *Server) matches(item *model.Item, q Query) bool {
+	if q.State != "" {
+		if q.State == "none" {
+			if item.State != model.StateNone {
+				return false
+			}
+		} else if !strings.EqualFold(q.State, string(item.State)) {
+			return false
+		}
+	}
+	if q.Open && (item.State == model.StateNone || s.config.IsDoneState(string(item.State))) {
+		return false
+	}
+	if q.Priority != "" && !strings.EqualFold(q.Priority, string(item.Priority)) {
+		return false
+	}
+	if q.Tag != "" {
+		found := false
+		for _, tag := range item.Tags {
+			if strings.EqualFold(tag, q.Tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(item.Title), text) &&
+			!strings.Contains(strings.ToLower(strings.Join(item.Notes, "\n")), text) {
+			return false
+		}
+	}
+	return true
+}
+
+// find resolves an item reference: an :ID: property, or the item's position in the outline
+// as dot-separated indexes ("0.2" is the third child of the first top-level item)
+func (s *Server) find(orgFile *model.OrgFile, ref string) (*model.Item, error) {
+	if item := orgFile.FindByID(ref); item != nil {
+		return item, nil
+	}
+
+	items := orgFile.Items
+	var item *model.Item
+	for _, part := range strings.Split(ref, ".") {
+		index, err := strconv.Atoi(part)
+		if err != nil || index < 0 || index >= len(items) {
+			return nil, errorf(http.StatusNotFound, "no item %q", ref)
+		}
+		item = items[index]
+		items = item.Children
+	}
+	if item == nil {
+		return nil, errorf(http.StatusNotFound, "no item %q", ref)
+	}
+	return item, nil
+}
+
+// itemResult converts an item, looking up its ref and outline path
+func (s *Server) itemResult(orgFile *model.OrgFile, target *model.Item) Item {
+	var result Item
+	walkItems(orgFile.Items, "", nil, func(item *model.Item, ref string, path []string) {
+		if item == target {
+			result = s.toAPI(orgFile, item, ref, path)
+		}
+	})
+	return result
+}
+
+// toAPI converts an item to its API representation
+func (s *Server) toAPI(orgFile *model.OrgFile, item *model.Item, ref string, path []string) Item {
+	result := Item{
+		Ref:          itemRef(item, ref),
+		Path:         path,
+		Level:        item.Level,
+		State:        string(item.State),
+		Priority:     string(item.Priority),
+		Title:        item.Title,
+		Tags:         item.Tags,
+		Scheduled:    item.Scheduled,
+		ScheduledEnd: item.ScheduledEnd,
+		Deadline:     item.Deadline,
+		DeadlineEnd:  item.DeadlineEnd,
+		Closed:       item.Closed,
+		Effort:       item.Effort,
+		Properties:   item.Properties,
+		Notes:        parser.StripMetadata(item.Notes),
+		ClockedIn:    item.IsClockedIn(),
+		ClockMinutes: int(item.GetTotalClockDuration().Minutes()),
+		SourceFile:   item.SourceFile,
+	}
+	if result.Tags == nil {
+		result.Tags = []string{}
+	}
+	if result.Path == nil {
+		result.Path = []string{}
+	}
+	for i, child := range item.Children {
+		result.Children = append(result.Children, itemRef(child, ref+"."+strconv.Itoa(i)))
+	}
+	return result
+}
+
+// validateState checks that a state is configured, allowing the empty state
+func (s *Server) validateState(state string) error {
+	if state == "" {
+		return nil
+	}
+	for _, name := range s.config.GetStateNames() {
+		if name == state {
+			return nil
+		}
+	}
+	return errorf(http.StatusBadRequest, "unknown state %q", state)
+}
+
+// walkItems calls fn for every item with its positional ref and the titles above it
+func walkItems(items []*model.Item, prefix string, path []string, fn func(item *model.Item, ref string, path []string)) {
+	for i, item := range items {
+		ref := strconv.Itoa(i)
+		if prefix != "" {
+			ref = prefix + "." + ref
+		}
+		fn(item, ref, path)
+		walkItems(item.Children, ref, append(append([]string{}, path...), item.Title), fn)
+	}
+}
+
+// itemRef prefers the item's :ID: over its position, since IDs survive edits elsewhere in the file
+func itemRef(item *model.Item, position string) string {
+	if id := item.GetProperty("ID"); id != "" {
+		return id
+	}
+	return position
+}
+
+// removeItem removes target from the tree
+func removeItem(items []*model.Item, target *model.Item) []*model.Item {
+	result := []*model.Item{}
+	for _, item := range items {
+		if item == target {
+			continue
+		}
+		item.Children = removeItem(item.Children, target)
+		result = append(result, item)
+	}
+	return result
+}
+
+// moveItem shifts the levels of an item and its children and sets the file they belong to
+func moveItem(item *model.Item, levelDelta int, sourceFile string) {
+	item.Level += levelDelta
+	item.SourceFile = sourceFile
+	for _, child := range item.Children {
+		moveItem(child, levelDelta, sourceFile)
+	}
+}
+
+// isMultiFile reports whether the top-level items stand for the files of a directory
+func isMultiFile(orgFile *model.OrgFile) bool {
+	return len(orgFile.Items) > 0 && orgFile.Items[0].SourceFile != ""
+}
+
+// setPriority validates and sets a priority, where an empty value clears it
+func setPriority(item *model.Item, priority string) error {
+	switch strings.ToUpper(priority) {
+	case "":
+		item.Priority = model.PriorityNone
+	case "A", "B", "C":
+		item.Priority = model.Priority(strings.ToUpper(priority))
+	default:
+		return errorf(http.StatusBadRequest, "invalid priority %q", priority)
+	}
+	return nil
+}
+
+// dateOnlyFormat is the accepted date format without a time of day
+const dateOnlyFormat = "2006-01-02"
+
+// dateFormats are the date formats accepted by the API
+var dateFormats = []string{
+	dateOnlyFormat,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	time.RFC3339,
+}
+
+// timeRangeEndPattern matches the end of a time range like "2025-01-06 14:00-15:00"
+var timeRangeEndPattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}[ T]\d{1,2}:\d{2})-(\d{1,2}:\d{2})$`)
+
+// parseDate parses an optional date, where an empty value means no date. The date may
+// be a time range like "2025-01-06 14:00-15:00", or a range of days like
+// "2025-01-06--2025-01-08", in which case its end is returned too. hasTime tells whether
+// the date was given with a time of day. Dates are read as wall clock times in loc.
+func parseDate(value string, loc *time.Location) (start, end *time.Time, hasTime bool, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil, false, nil
+	}
+
+	endValue := ""
+	if startValue, last, ok := strings.Cut(value, "--"); ok {
+		value, endValue = strings.TrimSpace(startValue), strings.TrimSpace(last)
+	} else if matches := timeRangeEndPattern.FindStringSubmatch(value); matches != nil {
+		value, endValue = matches[1], matches[2]
+	}
+
+	start, hasTime, err = parseDateValue(value, loc)
+	if err != nil || endValue == "" {
+		return start, nil, hasTime, err
+	}
+	if clock, clockErr := time.Parse("15:04", endValue); clockErr == nil {
+		// A time range ends on the day it starts
+		e := time.Date(start.Year(), start.Month(), start.Day(), clock.Hour(), clock.Minute(), 0, 0, start.Location())
+		end = &e
+	} else if end, _, err = parseDateValue(endValue, loc); err != nil {
+		return nil, nil, false, err
+	}
+	if !end.After(*start) {
+		return nil, nil, false, errorf(http.StatusBadRequest, "date range %q ends before it starts", value+"--"+endValue)
+	}
+	return start, end, hasTime, nil
+}
+
+// parseDateValue parses a single date in one of the accepted formats, reporting whether
+// it has a time of day
+func parseDateValue(value string, loc *time.Location) (*time.Time, bool, error) {
+	for _, format := range dateFormats {
+		if t, err := time.ParseInLocation(format, value, loc); err == nil {
+			t = t.In(loc)
+			return &t, format != dateOnlyFormat, nil
+		}
+	}
+	return nil, false, errorf(http.StatusBadRequest, "invalid date %q, use YYYY-MM-DD or YYYY-MM-DD HH:MM", value)
+}
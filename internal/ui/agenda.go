@@ -1,15 +1,18 @@
 package ui
 
 import (
+	"sort"
 	"time"
 
 	"github.com/rwejlgaard/org/internal/model"
 )
 
-// getAgendaItems returns items with scheduling or deadlines within the next 7 days
+// getAgendaItems returns items with scheduling, deadlines or appointments within the next
+// 7 days, in the order of their dates and times
 func (m uiModel) getAgendaItems() []*model.Item {
 	var items []*model.Item
-	now := time.Now()
+	dates := make(map[*model.Item]time.Time)
+	now := m.config.Now()
 	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	endOfWeek := startOfDay.AddDate(0, 0, 7)
 
@@ -17,16 +20,51 @@ func (m uiModel) getAgendaItems() []*model.Item {
 	var getAllItems func([]*model.Item)
 	getAllItems = func(list []*model.Item) {
 		for _, item := range list {
-			if item.Scheduled != nil && item.Scheduled.Before(endOfWeek) {
-				items = append(items, item)
-			}
-			if item.Deadline != nil && item.Deadline.Before(endOfWeek) {
+			if date, ok := agendaDate(item, startOfDay, endOfWeek, m.config.GetDeadlineWarningDays()); ok {
 				items = append(items, item)
+				dates[item] = date
 			}
 			getAllItems(item.Children)
 		}
 	}
 	getAllItems(m.orgFile.Items)
 
+	// Items keep their outline order on the same date and time
+	sort.SliceStable(items, func(i, j int) bool {
+		return dates[items[i]].Before(dates[items[j]])
+	})
 	return items
 }
+
+// agendaDate returns the date an item is listed under in an agenda from one day until
+// another: the earliest of its scheduled date, deadline and next appointment. Scheduled
+// dates and deadlines before the agenda stay on it until they are done with, and later
+// deadlines are on it once their warning period has started by the first day.
+func agendaDate(item *model.Item, from, until time.Time, warningDays int) (date time.Time, ok bool) {
+	consider := func(t time.Time, listed bool) {
+		if (listed || t.Before(until)) && (!ok || t.Before(date)) {
+			date, ok = t, true
+		}
+	}
+	if item.Scheduled != nil {
+		consider(*item.Scheduled, false)
+	}
+	if item.Deadline != nil {
+		consider(*item.Deadline, model.DaysUntil(from, item.DeadlineWarningStart(warningDays)) <= 0)
+	}
+	if appointment, found := nextAppointment(item, from); found {
+		consider(appointment.Start, false)
+	}
+	return date, ok
+}
+
+// nextAppointment returns the earliest occurrence of the item's plain timestamps that
+// isn't over before from
+func nextAppointment(item *model.Item, from time.Time) (next model.Timestamp, ok bool) {
+	for _, ts := range item.Timestamps {
+		if occurrence, found := ts.Occurrence(from); found && (!ok || occurrence.Start.Before(next.Start)) {
+			next, ok = occurrence, true
+		}
+	}
+	return next, ok
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/hooks"
 	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
 )
 
 type viewMode int
@@ -20,6 +22,7 @@ const (
 	modeEdit
 	modeConfirmDelete
 	modeCapture
+	modeCaptureTemplate
 	modeAddSubTask
 	modeSetDeadline
 	modeSetScheduled
@@ -29,6 +32,9 @@ const (
 	modeSettings
 	modeTagEdit
 	modeRename
+	modeStateNote
+	modeHistory
+	modeExport
 )
 
 type uiModel struct {
@@ -50,13 +56,28 @@ type uiModel struct {
 	textinput       textinput.Model
 	itemToDelete    *model.Item
 	reorderMode     bool
-	settingsCursor  int             // Cursor position in settings view
-	settingsScroll  int             // Scroll position in settings view
-	settingsSection settingsSection // Current settings section/tab
-	captureCursor   int             // Store cursor position when entering capture mode
+	settingsCursor  int                     // Cursor position in settings view
+	settingsScroll  int                     // Scroll position in settings view
+	settingsSection settingsSection         // Current settings section/tab
+	captureCursor   int                     // Store cursor position when entering capture mode
+	historyScroll   int                     // Scroll position in the history panel
+	pendingChange   *model.StateChange      // State change waiting for a note before being logged
+	pendingHooks    []*hooks.Invocation     // Hooks to start once the current update is done
+	pendingCommits  []*model.OrgFile        // Saved files to commit to git with the pending hooks
+	captureTemplate *config.CaptureTemplate // Template of the capture in progress, nil for a plain capture
+	capturePrompts  []string                // Values the capture still asks for, "" being the captured text
+	captureValues   map[string]string       // Values entered for the capture so far
+	calendarDate    time.Time               // Day picked in the calendar of the date prompt
+	calendarFocused bool                    // Whether the arrow keys move around the calendar rather than the input
+	exportOverwrite string                  // Existing file the export waits for a second Enter to replace
+	pomodoro        *pomodoroTimer          // Running pomodoro timer, nil if there is none
+	pomodoroRuns    int                     // Pomodoro timers started, to tell their ticks apart
+	shownReminders  map[string]bool         // Reminders only shown in the status line, by key
 }
 
-func InitialModel(orgFile *model.OrgFile, cfg *config.Config, captureMode bool, captureText string) uiModel {
+// InitialModel creates the UI model. In capture mode it starts capturing captureText,
+// with the named capture template if there is one.
+func InitialModel(orgFile *model.OrgFile, cfg *config.Config, captureMode bool, captureText, template string) uiModel {
 	ta := textarea.New()
 	ta.Placeholder = "Enter notes here (code blocks supported)..."
 	ta.ShowLineNumbers = false
@@ -68,30 +89,40 @@ func InitialModel(orgFile *model.OrgFile, cfg *config.Config, captureMode bool,
 	h := help.New()
 	h.ShowAll = false
 
-	mode := modeList
-	if captureMode {
-		mode = modeCapture
-		ti.SetValue(strings.TrimSpace(captureText))
-	}
-
-	return uiModel{
+	m := uiModel{
 		orgFile:   orgFile,
 		cursor:    0,
-		mode:      mode,
+		mode:      modeList,
 		help:      h,
 		keys:      newKeyMapFromConfig(cfg),
 		styles:    newStyleMapFromConfig(cfg),
 		config:    cfg,
 		textarea:  ta,
 		textinput: ti,
+
+		shownReminders: make(map[string]bool),
 	}
+
+	if captureMode {
+		captureText = strings.TrimSpace(captureText)
+		if tmpl := cfg.GetCaptureTemplate(template); tmpl != nil {
+			m.beginCapture(tmpl, captureText)
+		} else {
+			m.startCapture(captureText)
+		}
+	}
+	return m
 }
 
 func (m uiModel) Init() tea.Cmd {
+	var cmds []tea.Cmd
 	if m.mode == modeCapture {
-		return textinput.Blink
+		cmds = append(cmds, textinput.Blink)
+	}
+	if m.config.Notify.Enabled {
+		cmds = append(cmds, func() tea.Msg { return reminderTickMsg{} })
 	}
-	return nil
+	return tea.Batch(cmds...)
 }
 
 func (m *uiModel) setStatus(msg string) {
@@ -120,7 +151,7 @@ func (m *uiModel) updateScrollOffset(availableHeight int) {
 			// Count note lines with wrapping
 			indent := strings.Repeat("  ", item.Level)
 			noteIndent := indent + "  "
-			filteredNotes := filterLogbookDrawer(item.Notes)
+			filteredNotes := parser.StripMetadata(item.Notes)
 			wrappedNotes := wrapNoteLines(filteredNotes, m.width, noteIndent)
 			highlightedNotes := m.renderNotesWithHighlighting(wrappedNotes)
 			lineCount += len(highlightedNotes)
@@ -150,11 +181,8 @@ func (m *uiModel) updateScrollOffset(availableHeight int) {
 }
 
 // RunUI starts the terminal UI
-func RunUI(orgFile *model.OrgFile, cfg *config.Config, captureMode bool, captureText string) error {
-	m := InitialModel(orgFile, cfg, captureMode, captureText)
-	if captureMode {
-		m.textinput.Focus()
-	}
+func RunUI(orgFile *model.OrgFile, cfg *config.Config, captureMode bool, captureText, template string) error {
+	m := InitialModel(orgFile, cfg, captureMode, captureText, template)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	_, err := p.Run()
 	return err
@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// moveCalendar moves the day picked in the calendar of the date prompt and puts it in
+// the text input, keeping a time that was typed
+func (m *uiModel) moveCalendar(dateType string, days, months int) {
+	m.calendarDate = addMonths(m.calendarDate, months).AddDate(0, 0, days)
+
+	value := m.calendarDate.Format("2006-01-02")
+	if parsed, err := parseDateInput(m.textinput.Value(), m.dateInputBase(dateType), m.config.Now()); err == nil && parsed.hasTime {
+		value += parsed.date.Format(" 15:04")
+		if parsed.end != nil {
+			value += parsed.end.Format("-15:04")
+		}
+	}
+	m.textinput.SetValue(value)
+	m.textinput.CursorEnd()
+}
+
+// followDateInput moves the calendar of the date prompt to the date typed in the input
+func (m *uiModel) followDateInput(dateType string) {
+	if parsed, err := parseDateInput(m.textinput.Value(), m.dateInputBase(dateType), m.config.Now()); err == nil {
+		m.calendarDate = parsed.date
+	}
+}
+
+// addMonths moves t by a number of months, staying on the last day of the month when
+// the day doesn't exist in the month moved to, where AddDate would spill into the next
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// calendarKey handles the keys that move around the calendar while it has the focus,
+// reporting whether the key was one of them. Arrows move by days and weeks, shift-arrows
+// by months.
+func (m *uiModel) calendarKey(dateType, key string) bool {
+	switch key {
+	case "left":
+		m.moveCalendar(dateType, -1, 0)
+	case "right":
+		m.moveCalendar(dateType, 1, 0)
+	case "up":
+		m.moveCalendar(dateType, -7, 0)
+	case "down":
+		m.moveCalendar(dateType, 7, 0)
+	case "shift+left", "shift+up":
+		m.moveCalendar(dateType, 0, -1)
+	case "shift+right", "shift+down":
+		m.moveCalendar(dateType, 0, 1)
+	default:
+		return false
+	}
+	return true
+}
+
+// focusCalendar gives the arrow keys to the calendar, or back to the text input
+func (m *uiModel) focusCalendar(focused bool) tea.Cmd {
+	m.calendarFocused = focused
+	if focused {
+		m.textinput.Blur()
+		return nil
+	}
+	return m.textinput.Focus()
+}
+
+// calendarMarks returns the days that have deadlines and scheduled items
+func (m uiModel) calendarMarks() (deadlines, scheduled map[string]bool) {
+	deadlines = make(map[string]bool)
+	scheduled = make(map[string]bool)
+	for _, item := range m.orgFile.AllItems() {
+		if item.Deadline != nil {
+			deadlines[item.Deadline.Format("2006-01-02")] = true
+		}
+		if item.Scheduled != nil {
+			scheduled[item.Scheduled.Format("2006-01-02")] = true
+		}
+	}
+	return deadlines, scheduled
+}
+
+// renderCalendar draws the month of the picked day as a grid starting on Monday. The
+// picked day is highlighted, today is underlined, and days with deadlines or scheduled
+// items are colored.
+func (m uiModel) renderCalendar() string {
+	picked := m.calendarDate
+	first := time.Date(picked.Year(), picked.Month(), 1, 0, 0, 0, 0, picked.Location())
+	today := m.config.Now().Format("2006-01-02")
+	deadlines, scheduled := m.calendarMarks()
+
+	var b strings.Builder
+	header := first.Format("January 2006")
+	b.WriteString(m.styles.titleStyle.Render(fmt.Sprintf("%*s", (20+len(header))/2, header)))
+	b.WriteString("\n")
+	b.WriteString(m.styles.statusStyle.Render("Mo Tu We Th Fr Sa Su"))
+	b.WriteString("\n")
+
+	// Pad the first week up to the first day
+	offset := (int(first.Weekday()) + 6) % 7
+	b.WriteString(strings.Repeat("   ", offset))
+
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		style := lipgloss.NewStyle()
+		switch {
+		case deadlines[key]:
+			style = m.styles.overdueStyle
+		case scheduled[key]:
+			style = m.styles.scheduledStyle
+		}
+		if key == today {
+			style = style.Underline(true)
+		}
+		if day.Day() == picked.Day() {
+			style = style.Inherit(m.styles.cursorStyle).Bold(true)
+		}
+		b.WriteString(style.Render(fmt.Sprintf("%2d", day.Day())))
+
+		if day.Weekday() == time.Sunday {
+			b.WriteString("\n")
+		} else {
+			b.WriteString(" ")
+		}
+	}
+
+	if last := first.AddDate(0, 1, -1); last.Weekday() != time.Sunday {
+		b.WriteString("\n")
+	}
+	b.WriteString(m.styles.overdueStyle.Render("■") + m.styles.statusStyle.Render(" deadline  "))
+	b.WriteString(m.styles.scheduledStyle.Render("■") + m.styles.statusStyle.Render(" scheduled"))
+	return b.String()
+}
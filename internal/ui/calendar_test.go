@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		from   string
+		months int
+		want   string
+	}{
+		{"2025-01-31", 1, "2025-02-28"},
+		{"2024-01-31", 1, "2024-02-29"},
+		{"2025-03-31", -1, "2025-02-28"},
+		{"2025-05-31", 1, "2025-06-30"},
+		{"2025-12-15", 1, "2026-01-15"},
+		{"2025-01-15", -1, "2024-12-15"},
+	}
+	for _, tt := range tests {
+		from, _ := time.ParseInLocation("2006-01-02", tt.from, time.Local)
+		if got := addMonths(from, tt.months).Format("2006-01-02"); got != tt.want {
+			t.Errorf("addMonths(%s, %d) = %s; want %s", tt.from, tt.months, got, tt.want)
+		}
+	}
+}
+
+func TestCalendarKeys(t *testing.T) {
+	cfg := config.DefaultConfig()
+	deadline := time.Date(2025, 1, 31, 0, 0, 0, 0, cfg.Zone())
+	item := &model.Item{Level: 1, State: "TODO", Title: "Report", Deadline: &deadline}
+	m := InitialModel(&model.OrgFile{Items: []*model.Item{item}}, cfg, false, "", "")
+	m.mode = modeSetDeadline
+	m.editingItem = item
+	m.calendarDate = deadline
+	m.focusCalendar(true)
+
+	press := func(msg tea.KeyMsg) {
+		next, _ := m.updateSetDeadline(msg)
+		m = next.(uiModel)
+	}
+	steps := []struct {
+		key  tea.KeyMsg
+		want string
+	}{
+		{tea.KeyMsg{Type: tea.KeyRight}, "2025-02-01"},
+		{tea.KeyMsg{Type: tea.KeyUp}, "2025-01-25"},
+		{tea.KeyMsg{Type: tea.KeyShiftRight}, "2025-02-25"},
+		{tea.KeyMsg{Type: tea.KeyShiftUp}, "2025-01-25"},
+		{tea.KeyMsg{Type: tea.KeyDown}, "2025-02-01"},
+	}
+	for _, step := range steps {
+		press(step.key)
+		if got := m.textinput.Value(); got != step.want {
+			t.Errorf("after %s the input is %q; want %q", step.key, got, step.want)
+		}
+	}
+
+	// Typing goes to the input, where the arrows move the cursor
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" 14:00")})
+	press(tea.KeyMsg{Type: tea.KeyLeft})
+	if m.calendarFocused || m.textinput.Value() != "2025-02-01 14:00" {
+		t.Errorf("after typing the input is %q (calendar focused %v); want %q in the input", m.textinput.Value(), m.calendarFocused, "2025-02-01 14:00")
+	}
+
+	// Back in the calendar the time is kept, and Enter confirms the picked day
+	press(tea.KeyMsg{Type: tea.KeyTab})
+	press(tea.KeyMsg{Type: tea.KeyRight})
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := item.Deadline.Format("2006-01-02 15:04"); got != "2025-02-02 14:00" || !item.DeadlineHasTime {
+		t.Errorf("deadline = %s; want 2025-02-02 14:00", got)
+	}
+}
@@ -0,0 +1,243 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rwejlgaard/org/internal/capture"
+	"github.com/rwejlgaard/org/internal/config"
+	"github.com/rwejlgaard/org/internal/hooks"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// startCapture begins capturing text, offering the capture templates first if there are any
+func (m *uiModel) startCapture(text string) tea.Cmd {
+	if len(m.config.Capture.Templates) == 0 {
+		return m.beginCapture(nil, text)
+	}
+	m.mode = modeCaptureTemplate
+	m.captureValues = map[string]string{"": text}
+	return nil
+}
+
+// beginCapture asks for the values the template needs, or captures right away if it
+// doesn't need any. tmpl is nil for a plain capture.
+func (m *uiModel) beginCapture(tmpl *config.CaptureTemplate, text string) tea.Cmd {
+	m.captureTemplate = tmpl
+	m.capturePrompts = capture.Prompts(tmpl)
+	m.captureValues = map[string]string{"": text}
+	if len(m.capturePrompts) == 0 {
+		m.finishCapture()
+		return nil
+	}
+
+	m.mode = modeCapture
+	m.showCapturePrompt()
+	return textinput.Blink
+}
+
+// showCapturePrompt sets up the text input for the next value of the capture
+func (m *uiModel) showCapturePrompt() {
+	name := m.capturePrompts[0]
+	m.textinput.SetValue(m.captureValues[name])
+	m.textinput.Placeholder = name
+	if name == "" {
+		m.textinput.Placeholder = "What needs doing?"
+	}
+	m.textinput.Focus()
+}
+
+// finishCapture creates the entry from the values entered and files it
+func (m *uiModel) finishCapture() {
+	m.mode = modeList
+	m.textinput.Blur()
+
+	now := m.config.Now()
+	item := capture.NewItem(m.captureTemplate, m.config, m.captureValues, now)
+	if item.Title == "" {
+		m.setStatus("Nothing to capture")
+		return
+	}
+
+	// Without a target file, captures go to the file of the highlighted item in multi-file mode
+	var defaultFile *model.Item
+	if len(m.orgFile.Items) > 0 && m.orgFile.Items[0].SourceFile != "" {
+		defaultFile = m.findTopLevelFileItem(m.getVisibleItems(), m.captureCursor)
+	}
+
+	result, err := capture.Insert(m.orgFile, m.config, m.captureTemplate, item, defaultFile, now)
+	if err != nil {
+		m.setStatus(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	if result.Saved != nil {
+		m.pendingCommits = append(m.pendingCommits, result.Saved)
+		m.queueFileHook(hooks.Captured, item, result.File, nil)
+	} else {
+		m.queueHook(hooks.Captured, item, nil)
+	}
+
+	switch {
+	case m.captureTemplate != nil:
+		target := filepath.Base(result.File)
+		if result.Parent != nil && (len(m.captureTemplate.Heading) > 0 || m.captureTemplate.DateTree) {
+			target += " › " + result.Parent.Title
+		}
+		m.setStatus("Captured to " + target)
+	case defaultFile != nil:
+		m.setStatus("TODO captured to " + defaultFile.Title)
+	default:
+		m.setStatus("TODO captured!")
+	}
+}
+
+func (m uiModel) updateCapture(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.textinput.Width = 50
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyEnter:
+			name := m.capturePrompts[0]
+			value := strings.TrimSpace(m.textinput.Value())
+			if name == "" && value == "" {
+				// Nothing to capture
+				m.mode = modeList
+				m.textinput.Blur()
+				return m, nil
+			}
+			m.captureValues[name] = value
+			m.capturePrompts = m.capturePrompts[1:]
+			if len(m.capturePrompts) > 0 {
+				m.showCapturePrompt()
+				return m, nil
+			}
+			// Don't reset cursor, keep it where it was
+			m.finishCapture()
+			return m, nil
+		case tea.KeyEsc:
+			m.mode = modeList
+			m.textinput.Blur()
+			m.setStatus("Cancelled")
+			return m, nil
+		}
+	}
+
+	m.textinput, cmd = m.textinput.Update(msg)
+	return m, cmd
+}
+
+func (m uiModel) updateCaptureTemplate(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyEnter:
+			return m, m.beginCapture(nil, m.captureValues[""])
+		case tea.KeyEsc:
+			m.mode = modeList
+			m.setStatus("Cancelled")
+			return m, nil
+		case tea.KeyRunes:
+			for i := range m.config.Capture.Templates {
+				tmpl := &m.config.Capture.Templates[i]
+				if tmpl.Key == string(msg.Runes) {
+					return m, m.beginCapture(tmpl, m.captureValues[""])
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m uiModel) viewCapture() string {
+	dialogStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("99")).
+		Padding(1, 2).
+		Width(60)
+
+	var content strings.Builder
+	if m.captureTemplate != nil {
+		content.WriteString(m.styles.titleStyle.Render("Capture: " + templateName(m.captureTemplate)))
+	} else {
+		content.WriteString(m.styles.titleStyle.Render("Capture TODO"))
+	}
+	if name := m.capturePrompts[0]; name != "" {
+		content.WriteString("\n")
+		content.WriteString(m.styles.statusStyle.Render(name + ":"))
+	}
+	content.WriteString("\n\n")
+	content.WriteString(m.textinput.View())
+	content.WriteString("\n\n")
+	if len(m.capturePrompts) > 1 {
+		content.WriteString(m.styles.statusStyle.Render("Press Enter to continue • ESC to cancel"))
+	} else {
+		content.WriteString(m.styles.statusStyle.Render("Press Enter to save • ESC to cancel"))
+	}
+
+	dialog := dialogStyle.Render(content.String())
+
+	// Center the dialog horizontally and vertically
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, dialog)
+}
+
+func (m uiModel) viewCaptureTemplate() string {
+	dialogStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("99")).
+		Padding(1, 2).
+		Width(60)
+
+	var content strings.Builder
+	content.WriteString(m.styles.titleStyle.Render("Capture"))
+	content.WriteString("\n\n")
+	for _, tmpl := range m.config.Capture.Templates {
+		line := fmt.Sprintf("[%s] %s", tmpl.Key, templateName(&tmpl))
+		if target := templateTarget(&tmpl); target != "" {
+			line += m.styles.statusStyle.Render("  → " + target)
+		}
+		content.WriteString(line)
+		content.WriteString("\n")
+	}
+	content.WriteString("\n")
+	content.WriteString(m.styles.statusStyle.Render("Key: use template • Enter: plain TODO • ESC: cancel"))
+
+	dialog := dialogStyle.Render(content.String())
+
+	// Center the dialog horizontally and vertically
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, dialog)
+}
+
+// templateName returns the name of a template, falling back to its key
+func templateName(tmpl *config.CaptureTemplate) string {
+	if tmpl.Name != "" {
+		return tmpl.Name
+	}
+	return tmpl.Key
+}
+
+// templateTarget describes where a template files its entries
+func templateTarget(tmpl *config.CaptureTemplate) string {
+	parts := append([]string{}, tmpl.Heading...)
+	if tmpl.File != "" {
+		parts = append([]string{tmpl.File}, parts...)
+	}
+	if tmpl.DateTree {
+		parts = append(parts, "date tree")
+	}
+	return strings.Join(parts, " › ")
+}
@@ -0,0 +1,284 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Patterns for the parts of a date prompt
+var (
+	relativeInputPattern = regexp.MustCompile(`^(\+\+|--|\+|-)(\d+)([dwmy]?)$`)
+	timeInputPattern     = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+	dayInputPattern      = regexp.MustCompile(`^\d{1,2}$`)
+	yearInputPattern     = regexp.MustCompile(`^\d{4}$`)
+)
+
+// weekdayNames maps the names and abbreviations of weekdays
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// dateInput is a date entered at a date prompt
+type dateInput struct {
+	date    time.Time  // The day, at the time given if there was one
+	hasTime bool       // Whether a time was given
+	end     *time.Time // End of a time range, if one was given
+}
+
+// String formats the date for the preview under the prompt
+func (d dateInput) String() string {
+	s := d.date.Format("Mon 2006-01-02")
+	if d.hasTime {
+		s += d.date.Format(" 15:04")
+		if d.end != nil {
+			s += d.end.Format("-15:04")
+		}
+	}
+	return s
+}
+
+// parseDateInput reads a date in the style of org's date prompt. Parts of the date that
+// are left out are taken from base, the date being edited (or today), preferring dates
+// after it:
+//
+//	2025-02-01, 2025/2/1, 02/01/2025   absolute dates
+//	15, jan 20, 20 jan 2026, 2/1       day, month and year filled in from base
+//	mon, fri, next fri                 the weekday on or after base, or the one after that
+//	today, tomorrow, yesterday, .      relative to today
+//	+3, +2w, -1m, +1y                  days, weeks, months or years from today
+//	++3, --2w                          the same from base
+//	14:00, 9:30-10:30, 2pm             a time or time range, alone or after a date
+//
+// Dates are returned in now's timezone, which should be the one the parser reads dates in.
+func parseDateInput(input string, base time.Time, now time.Time) (dateInput, error) {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	base = base.In(loc)
+	base = time.Date(base.Year(), base.Month(), base.Day(), 0, 0, 0, 0, loc)
+
+	// Split off the time, which may come anywhere
+	var result dateInput
+	var start, end time.Duration
+	var dateWords []string
+	for _, word := range strings.Fields(strings.ToLower(input)) {
+		if !result.hasTime {
+			if s, e, ok := parseTimeRange(word); ok {
+				result.hasTime = true
+				start, end = s, e
+				continue
+			}
+		}
+		dateWords = append(dateWords, word)
+	}
+	if len(dateWords) == 0 && !result.hasTime {
+		return dateInput{}, fmt.Errorf("no date given")
+	}
+
+	day, err := parseDay(dateWords, base, today)
+	if err != nil {
+		return dateInput{}, err
+	}
+
+	result.date = atTimeOfDay(day, start)
+	if result.hasTime && end >= 0 {
+		if end <= start {
+			return dateInput{}, fmt.Errorf("time range ends before it starts")
+		}
+		endTime := atTimeOfDay(day, end)
+		result.end = &endTime
+	}
+	return result, nil
+}
+
+// atTimeOfDay returns the wall clock time t on day. Adding t to midnight would be an hour
+// off on days when the clocks change.
+func atTimeOfDay(day time.Time, t time.Duration) time.Time {
+	hour, minute := int(t/time.Hour), int(t%time.Hour/time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+}
+
+// parseDay reads the date part of a date prompt, which may be empty to use base
+func parseDay(words []string, base, today time.Time) (time.Time, error) {
+	text := strings.Join(words, " ")
+	if text == "" {
+		return base, nil
+	}
+
+	switch text {
+	case ".", "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if match := relativeInputPattern.FindStringSubmatch(text); match != nil {
+		n, _ := strconv.Atoi(match[2])
+		from := today
+		if len(match[1]) == 2 {
+			from = base
+		}
+		if match[1][0] == '-' {
+			n = -n
+		}
+		switch match[3] {
+		case "w":
+			return from.AddDate(0, 0, 7*n), nil
+		case "m":
+			return from.AddDate(0, n, 0), nil
+		case "y":
+			return from.AddDate(n, 0, 0), nil
+		default:
+			return from.AddDate(0, 0, n), nil
+		}
+	}
+
+	// Weekdays, optionally "next" for the one a week later
+	next := false
+	if len(words) == 2 && words[0] == "next" {
+		next = true
+		words = words[1:]
+	}
+	if weekday, ok := weekdayNames[words[0]]; ok && len(words) == 1 {
+		day := base.AddDate(0, 0, (int(weekday)-int(base.Weekday())+7)%7)
+		if next {
+			day = day.AddDate(0, 0, 7)
+		}
+		return day, nil
+	}
+	if next {
+		return time.Time{}, fmt.Errorf("unknown weekday %q", words[0])
+	}
+
+	// Absolute dates
+	for _, format := range []string{"2006-01-02", "2006-1-2", "2006/01/02", "2006/1/2", "01/02/2006", "1/2/2006"} {
+		if t, err := time.ParseInLocation(format, text, today.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	// A day of the month
+	if dayInputPattern.MatchString(text) {
+		n, _ := strconv.Atoi(text)
+		if n < 1 || n > 31 {
+			return time.Time{}, fmt.Errorf("no day %d in a month", n)
+		}
+		for i := 0; i < 12; i++ {
+			month := base.AddDate(0, i, 1-base.Day())
+			day := time.Date(month.Year(), month.Month(), n, 0, 0, 0, 0, today.Location())
+			if day.Month() == month.Month() && !day.Before(base) {
+				return day, nil
+			}
+		}
+	}
+
+	// A month and day, like "jan 20", "20 jan", "1/20" or "jan 20 2026"
+	if month, day, year, ok := parseMonthDay(words); ok {
+		if year == 0 {
+			year = base.Year()
+			if time.Date(year, month, day, 0, 0, 0, 0, today.Location()).Before(base) {
+				year++
+			}
+		}
+		t := time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+		if t.Month() != month {
+			return time.Time{}, fmt.Errorf("no day %d in %s", day, month)
+		}
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("unable to read %q as a date", text)
+}
+
+// parseMonthDay reads a month and day in either order, with an optional year after them,
+// or a month/day pair. year is 0 if it wasn't given.
+func parseMonthDay(words []string) (month time.Month, day, year int, ok bool) {
+	if len(words) == 1 {
+		t, err := time.Parse("1/2", words[0])
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		return t.Month(), t.Day(), 0, true
+	}
+	if len(words) == 3 {
+		if !yearInputPattern.MatchString(words[2]) {
+			return 0, 0, 0, false
+		}
+		year, _ = strconv.Atoi(words[2])
+		words = words[:2]
+	}
+	if len(words) != 2 {
+		return 0, 0, 0, false
+	}
+
+	monthWord, dayWord := words[0], words[1]
+	if dayInputPattern.MatchString(monthWord) {
+		monthWord, dayWord = dayWord, monthWord
+	}
+	if !dayInputPattern.MatchString(dayWord) {
+		return 0, 0, 0, false
+	}
+	day, _ = strconv.Atoi(dayWord)
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		if len(monthWord) >= 3 && strings.HasPrefix(name, monthWord) {
+			return m, day, year, true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// parseTimeRange reads a time such as 14:00 or 2pm, or a range such as 9:30-10:30. A bare
+// number isn't a time, as it would be mistaken for a day. end is -1 if there is no range.
+func parseTimeRange(word string) (start, end time.Duration, ok bool) {
+	startWord, endWord, isRange := strings.Cut(word, "-")
+	start, explicit, ok := parseTime(startWord)
+	if !ok {
+		return 0, 0, false
+	}
+	if !isRange {
+		return start, -1, explicit
+	}
+	end, endExplicit, ok := parseTime(endWord)
+	if !ok || !(explicit || endExplicit) {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// parseTime reads a time of day. explicit is false for a bare number.
+func parseTime(word string) (t time.Duration, explicit bool, ok bool) {
+	match := timeInputPattern.FindStringSubmatch(word)
+	if match == nil {
+		return 0, false, false
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	if match[3] != "" {
+		// 12-hour clock
+		if hour < 1 || hour > 12 {
+			return 0, false, false
+		}
+		hour %= 12
+		if match[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, false, false
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, match[2] != "" || match[3] != "", true
+}
@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateInput(t *testing.T) {
+	// A Wednesday, editing a date on the following Monday
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.Local)
+	base := time.Date(2025, 1, 20, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2025-02-01", "Sat 2025-02-01"},
+		{"2/1/2026", "Sun 2026-02-01"},
+		{"today", "Wed 2025-01-15"},
+		{"tomorrow", "Thu 2025-01-16"},
+		{"+3", "Sat 2025-01-18"},
+		{"+2w", "Wed 2025-01-29"},
+		{"++1m", "Thu 2025-02-20"},
+		{"--1d", "Sun 2025-01-19"},
+		{"fri", "Fri 2025-01-24"},
+		{"next mon", "Mon 2025-01-27"},
+		{"25", "Sat 2025-01-25"},
+		{"10", "Mon 2025-02-10"},
+		{"jan 10", "Sat 2026-01-10"},
+		{"20 mar 2027", "Sat 2027-03-20"},
+		{"14:00", "Mon 2025-01-20 14:00"},
+		{"fri 2pm", "Fri 2025-01-24 14:00"},
+		{"9:30-10:45 tomorrow", "Thu 2025-01-16 09:30-10:45"},
+	}
+	for _, tt := range tests {
+		got, err := parseDateInput(tt.input, base, now)
+		if err != nil {
+			t.Errorf("parseDateInput(%q): %v", tt.input, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("parseDateInput(%q) = %s; want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseDateInputErrors(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.Local)
+	for _, input := range []string{"", "feb 30", "32", "next", "10:00-9:00", "someday"} {
+		if got, err := parseDateInput(input, now, now); err == nil {
+			t.Errorf("parseDateInput(%q) = %s; want an error", input, got)
+		}
+	}
+}
+
+func TestAtTimeOfDayOnDSTChange(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone data:", err)
+	}
+	// The clocks go forward an hour at 02:00 on this day
+	day := time.Date(2025, 3, 30, 0, 0, 0, 0, loc)
+	got := atTimeOfDay(day, 14*time.Hour+30*time.Minute)
+	if got.Hour() != 14 || got.Minute() != 30 || got.Day() != 30 {
+		t.Errorf("atTimeOfDay = %v; want 2025-03-30 14:30", got)
+	}
+}
@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rwejlgaard/org/internal/convert"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// slugPattern matches runs of characters that don't belong in a file name
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// defaultExportPath suggests a file next to the item's org file, named after the item
+func (m uiModel) defaultExportPath(item *model.Item) string {
+	dir := filepath.Dir(m.orgFile.Path)
+	if item.SourceFile != "" {
+		dir = filepath.Dir(item.SourceFile)
+	}
+
+	name := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(item.Title), "-"), "-")
+	if name == "" {
+		name = "export"
+	}
+	return filepath.Join(dir, name+".md")
+}
+
+// exportSubtree writes the item and its children to path, picking the format from the extension
+func (m uiModel) exportSubtree(item *model.Item, path string) error {
+	format := strings.ToLower(filepath.Ext(path))
+	switch format {
+	case ".md", ".markdown", ".html", ".htm":
+	default:
+		return fmt.Errorf("unsupported format %q, use .md or .html", filepath.Ext(path))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if format == ".html" || format == ".htm" {
+		err = convert.ExportHTML(file, []*model.Item{item}, m.config, convert.HTMLOptions{Title: item.Title})
+	} else {
+		err = convert.ExportMarkdown(file, []*model.Item{item}, m.config, convert.MarkdownOptions{})
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+func (m uiModel) updateExport(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.textinput.Width = 50
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyEnter:
+			path := strings.TrimSpace(m.textinput.Value())
+			if _, err := os.Stat(path); err == nil && path != m.exportOverwrite {
+				// Ask before replacing a file that is already there
+				m.exportOverwrite = path
+				return m, nil
+			}
+			m.exportOverwrite = ""
+			if m.editingItem != nil && path != "" {
+				if err := m.exportSubtree(m.editingItem, path); err != nil {
+					m.setStatus(fmt.Sprintf("Export failed: %v", err))
+				} else {
+					m.setStatus(fmt.Sprintf("Exported to %s", path))
+				}
+			}
+			m.mode = modeList
+			m.textinput.Blur()
+			m.editingItem = nil
+			return m, nil
+		case tea.KeyEsc:
+			m.mode = modeList
+			m.textinput.Blur()
+			m.editingItem = nil
+			m.exportOverwrite = ""
+			m.setStatus("Cancelled")
+			return m, nil
+		}
+	}
+
+	m.textinput, cmd = m.textinput.Update(msg)
+	return m, cmd
+}
+
+func (m uiModel) viewExport() string {
+	dialogStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("141")).
+		Padding(1, 2).
+		Width(60)
+
+	var content strings.Builder
+	content.WriteString(m.styles.titleStyle.Render("Export Subtree"))
+	content.WriteString("\n")
+	if m.editingItem != nil {
+		content.WriteString(m.styles.statusStyle.Render(fmt.Sprintf("For: %s", m.editingItem.Title)))
+	}
+	content.WriteString("\n\n")
+	content.WriteString(m.textinput.View())
+	content.WriteString("\n\n")
+	content.WriteString(m.styles.statusStyle.Render("Formats: .md (Markdown), .html (HTML page)"))
+	content.WriteString("\n")
+	if m.exportOverwrite != "" && m.exportOverwrite == strings.TrimSpace(m.textinput.Value()) {
+		content.WriteString(m.styles.overdueStyle.Render("The file already exists. Press Enter again to replace it • ESC to cancel"))
+	} else {
+		content.WriteString(m.styles.statusStyle.Render("Press Enter to export • ESC to cancel"))
+	}
+
+	dialog := dialogStyle.Render(content.String())
+
+	// Center the dialog horizontally and vertically
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, dialog)
+}
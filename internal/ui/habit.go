@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rwejlgaard/org/internal/model"
+	"github.com/rwejlgaard/org/internal/parser"
+)
+
+// Habit graph colors, following Emacs' org-habit faces
+var (
+	habitNotDueStyle  = lipgloss.NewStyle().Background(lipgloss.Color("27")).Foreground(lipgloss.Color("231"))  // Blue
+	habitDueSoonStyle = lipgloss.NewStyle().Background(lipgloss.Color("34")).Foreground(lipgloss.Color("231"))  // Green
+	habitDueStyle     = lipgloss.NewStyle().Background(lipgloss.Color("220")).Foreground(lipgloss.Color("16"))  // Yellow
+	habitOverdueStyle = lipgloss.NewStyle().Background(lipgloss.Color("196")).Foreground(lipgloss.Color("231")) // Red
+)
+
+// truncateToDay returns midnight at the start of t's day
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// getHabitCompletions returns the days on which a habit was completed, oldest first,
+// taken from the state changes recorded in its LOGBOOK
+func (m uiModel) getHabitCompletions(item *model.Item) []time.Time {
+	var days []time.Time
+	changes := parser.GetStateChanges(item, m.config.Zone())
+	// State changes are logged newest first
+	for i := len(changes) - 1; i >= 0; i-- {
+		if m.config.IsDoneState(string(changes[i].To)) {
+			days = append(days, truncateToDay(changes[i].Time))
+		}
+	}
+	return days
+}
+
+// renderHabitGraph renders an Emacs-style consistency graph for a habit: one cell per
+// day, colored by whether the habit was not yet due (blue), could be done (green), was
+// due that day (yellow) or overdue (red). Completed days show '*', today shows '!'.
+func (m uiModel) renderHabitGraph(item *model.Item) string {
+	repeater, ok := model.ParseRepeater(item.ScheduledRepeater)
+	if !ok || item.Scheduled == nil {
+		return ""
+	}
+
+	today := truncateToDay(m.config.Now())
+	completions := m.getHabitCompletions(item)
+	scheduled := truncateToDay(*item.Scheduled)
+
+	var graph strings.Builder
+	for offset := -m.config.UI.HabitDaysBefore; offset <= m.config.UI.HabitDaysAfter; offset++ {
+		day := today.AddDate(0, 0, offset)
+
+		// Work out when the habit was due as of this day
+		var due, deadline time.Time
+		var lastDone *time.Time
+		doneToday := false
+		for i := range completions {
+			if completions[i].Before(day) {
+				lastDone = &completions[i]
+			} else if completions[i].Equal(day) {
+				doneToday = true
+			}
+		}
+		switch {
+		case day.After(today):
+			// The future follows the current schedule
+			due = scheduled
+			deadline = scheduled.Add(repeater.MaxInterval(scheduled).Sub(repeater.Interval(scheduled)))
+		case lastDone != nil:
+			due = repeater.Interval(*lastDone)
+			deadline = repeater.MaxInterval(*lastDone)
+		default:
+			// Nothing known before the first completion
+			due = day.AddDate(0, 0, 1)
+			deadline = due
+		}
+
+		var style lipgloss.Style
+		switch {
+		case day.Before(due):
+			style = habitNotDueStyle
+		case day.Before(deadline):
+			style = habitDueSoonStyle
+		case day.Equal(deadline):
+			style = habitDueStyle
+		default:
+			style = habitOverdueStyle
+		}
+
+		cell := " "
+		if doneToday {
+			cell = "*"
+		} else if day.Equal(today) {
+			cell = "!"
+		}
+		graph.WriteString(style.Render(cell))
+	}
+
+	return graph.String()
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rwejlgaard/org/internal/gitsync"
+	"github.com/rwejlgaard/org/internal/hooks"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// hookResultMsg reports that a hook command, or a git commit, has finished
+type hookResultMsg struct {
+	err error
+}
+
+// queueHook prepares the hook for an event, if one is configured. Queued hooks are
+// started in the background once the current update is done.
+func (m *uiModel) queueHook(event string, item *model.Item, extra map[string]string) {
+	file := m.orgFile.Path
+	if item != nil && item.SourceFile != "" {
+		file = item.SourceFile
+	}
+	m.queueFileHook(event, item, file, extra)
+}
+
+// queueFileHook prepares the hook for an event about an item in a file other than the one
+// that is open
+func (m *uiModel) queueFileHook(event string, item *model.Item, file string, extra map[string]string) {
+	if inv := hooks.Prepare(m.config, event, item, file, extra); inv != nil {
+		m.pendingHooks = append(m.pendingHooks, inv)
+	}
+}
+
+// runPendingHooks returns a command that runs the queued hooks one after another,
+// after committing the files saved during the update to git
+func (m *uiModel) runPendingHooks() tea.Cmd {
+	if len(m.pendingHooks) == 0 && len(m.pendingCommits) == 0 {
+		return nil
+	}
+
+	var cmds []tea.Cmd
+	for _, orgFile := range m.pendingCommits {
+		orgFile, cfg := orgFile, m.config
+		cmds = append(cmds, func() tea.Msg {
+			if err := gitsync.CommitSaved(orgFile, cfg); err != nil {
+				return hookResultMsg{err: fmt.Errorf("Saved but not committed: %v", err)}
+			}
+			return hookResultMsg{}
+		})
+	}
+	m.pendingCommits = nil
+	for _, inv := range m.pendingHooks {
+		inv := inv
+		cmds = append(cmds, func() tea.Msg {
+			return hookResultMsg{err: inv.Run()}
+		})
+	}
+	m.pendingHooks = nil
+	return tea.Sequence(cmds...)
+}
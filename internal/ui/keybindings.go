@@ -36,6 +36,9 @@ type keyMap struct {
 	SetEffort     key.Binding
 	Settings      key.Binding
 	TagItem       key.Binding
+	ShowHistory   key.Binding
+	Export        key.Binding
+	Pomodoro      key.Binding
 }
 
 // newKeyMapFromConfig creates a keyMap from configuration
@@ -155,6 +158,18 @@ func newKeyMapFromConfig(cfg *config.Config) keyMap {
 			key.WithKeys(kb.TagItem...),
 			key.WithHelp(formatKeyHelp(kb.TagItem), "add/edit tags"),
 		),
+		ShowHistory: key.NewBinding(
+			key.WithKeys(kb.ShowHistory...),
+			key.WithHelp(formatKeyHelp(kb.ShowHistory), "state history"),
+		),
+		Export: key.NewBinding(
+			key.WithKeys(kb.Export...),
+			key.WithHelp(formatKeyHelp(kb.Export), "export subtree"),
+		),
+		Pomodoro: key.NewBinding(
+			key.WithKeys(kb.Pomodoro...),
+			key.WithHelp(formatKeyHelp(kb.Pomodoro), "start/stop pomodoro"),
+		),
 	}
 }
 
@@ -201,6 +216,6 @@ func (k keyMap) getAllBindings() []key.Binding {
 		k.ToggleFold, k.EditNotes, k.ToggleReorder,
 		k.Capture, k.AddSubTask, k.Delete, k.Save,
 		k.ClockIn, k.ClockOut, k.SetDeadline, k.SetScheduled, k.SetPriority, k.SetEffort,
-		k.TagItem, k.Settings, k.ToggleView, k.Help, k.Quit,
+		k.Pomodoro, k.TagItem, k.ShowHistory, k.Export, k.Settings, k.ToggleView, k.Help, k.Quit,
 	}
 }
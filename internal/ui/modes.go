@@ -9,11 +9,50 @@ import (
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rwejlgaard/org/internal/hooks"
 	"github.com/rwejlgaard/org/internal/model"
 	"github.com/rwejlgaard/org/internal/parser"
 )
 
 func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Hooks report back with their result whatever mode we're in
+	if msg, ok := msg.(hookResultMsg); ok {
+		if msg.err != nil {
+			m.setStatus(msg.err.Error())
+		}
+		return m, nil
+	}
+
+	// Reminders are checked in the background whatever mode we're in
+	switch msg := msg.(type) {
+	case reminderTickMsg:
+		return m, m.checkReminders()
+	case reminderResultMsg:
+		m.showReminders(msg)
+		return m, nil
+	case pomodoroTickMsg:
+		cmd := m.tickPomodoro(msg)
+		return m, tea.Batch(cmd, m.runPendingHooks())
+	}
+
+	next, cmd := m.update(msg)
+
+	// Start the hooks for whatever happened during the update
+	var hooksCmd tea.Cmd
+	switch updated := next.(type) {
+	case uiModel:
+		hooksCmd = updated.runPendingHooks()
+		next = updated
+	case *uiModel:
+		hooksCmd = updated.runPendingHooks()
+	}
+	if hooksCmd != nil {
+		return next, tea.Batch(cmd, hooksCmd)
+	}
+	return next, cmd
+}
+
+func (m uiModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Handle special modes
 	switch m.mode {
 	case modeEdit:
@@ -22,6 +61,8 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateConfirmDelete(msg)
 	case modeCapture:
 		return m.updateCapture(msg)
+	case modeCaptureTemplate:
+		return m.updateCaptureTemplate(msg)
 	case modeAddSubTask:
 		return m.updateAddSubTask(msg)
 	case modeSetDeadline:
@@ -44,6 +85,12 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateTagEdit(msg)
 	case modeRename:
 		return m.updateRename(msg)
+	case modeStateNote:
+		return m.updateStateNote(msg)
+	case modeHistory:
+		return m.updateHistory(msg)
+	case modeExport:
+		return m.updateExport(msg)
 	}
 
 	switch msg := msg.(type) {
@@ -101,23 +148,12 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			items := m.getVisibleItems()
 			if len(items) > 0 && m.cursor < len(items) {
 				m.cycleStateBackward(items[m.cursor])
-				// Auto clock out when changing to DONE
-				if items[m.cursor].State == model.StateDONE && items[m.cursor].IsClockedIn() {
-					items[m.cursor].ClockOut()
-				}
-				m.setStatus("State changed")
 			}
 
 		case key.Matches(msg, m.keys.Right):
 			items := m.getVisibleItems()
 			if len(items) > 0 && m.cursor < len(items) {
 				m.cycleStateForward(items[m.cursor])
-				// Auto clock out when changing to last state (typically DONE)
-				stateNames := m.config.GetStateNames()
-				if len(stateNames) > 0 && string(items[m.cursor].State) == stateNames[len(stateNames)-1] && items[m.cursor].IsClockedIn() {
-					items[m.cursor].ClockOut()
-				}
-				m.setStatus("State changed")
 			}
 
 		case key.Matches(msg, m.keys.ShiftUp):
@@ -136,12 +172,6 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			items := m.getVisibleItems()
 			if len(items) > 0 && m.cursor < len(items) {
 				m.cycleStateForward(items[m.cursor])
-				// Auto clock out when changing to last state (typically DONE)
-				stateNames := m.config.GetStateNames()
-				if len(stateNames) > 0 && string(items[m.cursor].State) == stateNames[len(stateNames)-1] && items[m.cursor].IsClockedIn() {
-					items[m.cursor].ClockOut()
-				}
-				m.setStatus("State changed")
 			}
 
 		case key.Matches(msg, m.keys.ToggleFold):
@@ -192,6 +222,26 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, textinput.Blink
 			}
 
+		case key.Matches(msg, m.keys.ShowHistory):
+			items := m.getVisibleItems()
+			if len(items) > 0 && m.cursor < len(items) {
+				m.editingItem = items[m.cursor]
+				m.mode = modeHistory
+				m.historyScroll = 0
+				return m, nil
+			}
+
+		case key.Matches(msg, m.keys.Export):
+			items := m.getVisibleItems()
+			if len(items) > 0 && m.cursor < len(items) {
+				m.editingItem = items[m.cursor]
+				m.mode = modeExport
+				m.textinput.SetValue(m.defaultExportPath(m.editingItem))
+				m.textinput.Placeholder = "path/to/file.md"
+				m.textinput.Focus()
+				return m, textinput.Blink
+			}
+
 		case key.Matches(msg, m.keys.Rename):
 			items := m.getVisibleItems()
 			if len(items) > 0 && m.cursor < len(items) {
@@ -204,12 +254,8 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, m.keys.Capture):
-			m.mode = modeCapture
 			m.captureCursor = m.cursor // Store current cursor position
-			m.textinput.SetValue("")
-			m.textinput.Placeholder = "What needs doing?"
-			m.textinput.Focus()
-			return m, textinput.Blink
+			return m, m.startCapture("")
 
 		case key.Matches(msg, m.keys.AddSubTask):
 			items := m.getVisibleItems()
@@ -242,6 +288,8 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.setStatus(fmt.Sprintf("Error saving: %v", err))
 			} else {
 				m.setStatus("Saved!")
+				m.pendingCommits = append(m.pendingCommits, m.orgFile)
+				m.queueHook(hooks.Saved, nil, nil)
 			}
 
 		case key.Matches(msg, m.keys.ToggleReorder):
@@ -255,8 +303,9 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.ClockIn):
 			items := m.getVisibleItems()
 			if len(items) > 0 && m.cursor < len(items) {
-				if items[m.cursor].ClockIn() {
+				if items[m.cursor].ClockIn(m.config.Now()) {
 					m.setStatus("Clocked in!")
+					m.queueHook(hooks.ClockIn, items[m.cursor], nil)
 				} else {
 					m.setStatus("Already clocked in")
 				}
@@ -265,22 +314,42 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.ClockOut):
 			items := m.getVisibleItems()
 			if len(items) > 0 && m.cursor < len(items) {
-				if items[m.cursor].ClockOut() {
+				if items[m.cursor].ClockOut(m.config.Now()) {
 					m.setStatus("Clocked out!")
+					m.queueHook(hooks.ClockOut, items[m.cursor], nil)
 				} else {
 					m.setStatus("Not clocked in")
 				}
 			}
 
+		case key.Matches(msg, m.keys.Pomodoro):
+			items := m.getVisibleItems()
+			if m.pomodoro != nil {
+				m.stopPomodoro()
+				m.setStatus("Pomodoro stopped")
+			} else if len(items) > 0 && m.cursor < len(items) {
+				selectedItem := items[m.cursor]
+
+				// File items in multi-file mode aren't headings, so their clock and
+				// pomodoro count would never be saved
+				isMultiFile := len(m.orgFile.Items) > 0 && m.orgFile.Items[0].SourceFile != ""
+				if isMultiFile && selectedItem.Level == 1 && selectedItem.SourceFile != "" {
+					m.setStatus("Cannot start a pomodoro on file-level items")
+					return m, nil
+				}
+				return m, m.startPomodoro(selectedItem)
+			}
+
 		case key.Matches(msg, m.keys.SetDeadline):
 			items := m.getVisibleItems()
 			if len(items) > 0 && m.cursor < len(items) {
 				m.editingItem = items[m.cursor]
 				m.mode = modeSetDeadline
+				m.calendarDate = m.dateInputBase("DEADLINE")
 				m.textinput.SetValue("")
-				m.textinput.Placeholder = "YYYY-MM-DD or +N (days from today)"
-				m.textinput.Focus()
-				return m, textinput.Blink
+				m.textinput.Placeholder = "e.g. fri, +2w, jan 20, tomorrow 14:00"
+				m.focusCalendar(true)
+				return m, nil
 			}
 
 		case key.Matches(msg, m.keys.SetScheduled):
@@ -288,10 +357,11 @@ func (m uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if len(items) > 0 && m.cursor < len(items) {
 				m.editingItem = items[m.cursor]
 				m.mode = modeSetScheduled
+				m.calendarDate = m.dateInputBase("SCHEDULED")
 				m.textinput.SetValue("")
-				m.textinput.Placeholder = "YYYY-MM-DD or +N (days from today)"
-				m.textinput.Focus()
-				return m, textinput.Blink
+				m.textinput.Placeholder = "e.g. fri, +2w, jan 20, tomorrow 14:00"
+				m.focusCalendar(true)
+				return m, nil
 			}
 
 		case key.Matches(msg, m.keys.SetPriority):
@@ -339,6 +409,7 @@ func (m uiModel) updateEditMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else {
 					m.editingItem.Notes = strings.Split(noteText, "\n")
 				}
+				parser.ScanTimestamps(m.editingItem, m.config.Zone())
 			}
 			m.mode = modeList
 			m.textarea.Blur()
@@ -357,6 +428,7 @@ func (m uiModel) updateConfirmDelete(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "y", "Y":
 			// Delete the item
+			m.queueHook(hooks.Deleted, m.itemToDelete, nil)
 			m.deleteItem(m.itemToDelete)
 			m.mode = modeList
 			m.itemToDelete = nil
@@ -375,74 +447,6 @@ func (m uiModel) updateConfirmDelete(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m uiModel) updateCapture(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var cmd tea.Cmd
-
-	switch msg := msg.(type) {
-	case tea.WindowSizeMsg:
-		m.width = msg.Width
-		m.height = msg.Height
-		m.textinput.Width = 50
-
-	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyEnter:
-			title := strings.TrimSpace(m.textinput.Value())
-			if title != "" {
-				// Get default state from config
-				defaultState := model.TodoState(m.config.GetDefaultNewTaskState())
-
-				// Create new TODO at top level
-				newItem := &model.Item{
-					Level:    1,
-					State:    defaultState,
-					Title:    title,
-					Notes:    []string{},
-					Children: []*model.Item{},
-				}
-
-				// Check if we're in multi-file mode
-				isMultiFile := len(m.orgFile.Items) > 0 && m.orgFile.Items[0].SourceFile != ""
-
-				if isMultiFile {
-					// In multi-file mode, add to the file of the highlighted item (using stored cursor position)
-					items := m.getVisibleItems()
-					targetFileItem := m.findTopLevelFileItem(items, m.captureCursor)
-
-					if targetFileItem != nil {
-						// Set the source file for the new item
-						newItem.SourceFile = targetFileItem.SourceFile
-						newItem.Level = 2 // Children of file items are level 2
-
-						// Insert at the beginning of the file item's children
-						targetFileItem.Children = append([]*model.Item{newItem}, targetFileItem.Children...)
-						targetFileItem.Folded = false // Unfold to show the new item
-						m.setStatus("TODO captured to " + targetFileItem.Title)
-					} else {
-						m.setStatus("Error: Could not find file to add to")
-					}
-				} else {
-					// Single file mode: insert at beginning
-					m.orgFile.Items = append([]*model.Item{newItem}, m.orgFile.Items...)
-					m.setStatus("TODO captured!")
-				}
-			}
-			m.mode = modeList
-			m.textinput.Blur()
-			// Don't reset cursor, keep it where it was
-			return m, nil
-		case tea.KeyEsc:
-			m.mode = modeList
-			m.textinput.Blur()
-			m.setStatus("Cancelled")
-			return m, nil
-		}
-	}
-
-	m.textinput, cmd = m.textinput.Update(msg)
-	return m, cmd
-}
-
 // findTopLevelFileItem finds the top-level file item that contains the item at the given cursor position
 func (m *uiModel) findTopLevelFileItem(items []*model.Item, cursorPos int) *model.Item {
 	if cursorPos < 0 || cursorPos >= len(items) {
@@ -512,6 +516,7 @@ func (m uiModel) updateAddSubTask(msg tea.Msg) (tea.Model, tea.Cmd) {
 					Children:   []*model.Item{},
 					SourceFile: m.editingItem.SourceFile, // Inherit source file from parent
 				}
+				parser.ScanTimestamps(newItem, m.config.Zone())
 				m.editingItem.Children = append(m.editingItem.Children, newItem)
 				m.editingItem.Folded = false // Unfold to show new sub-task
 				m.setStatus("Sub-task added!")
@@ -537,33 +542,18 @@ func (m uiModel) updateSetDeadline(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m.updateSetDate(msg, "DEADLINE")
 }
 
-// parseDateInput parses date input like "2024-01-15" or "+3" (3 days from now)
-func parseDateInput(input string) (time.Time, error) {
-	// Check if it's a relative date (+N days)
-	if strings.HasPrefix(input, "+") {
-		daysStr := strings.TrimPrefix(input, "+")
-		days := 0
-		_, err := fmt.Sscanf(daysStr, "%d", &days)
-		if err != nil {
-			return time.Time{}, fmt.Errorf("invalid relative date format: %s", input)
+// dateInputBase returns the date that a date prompt fills in missing parts from: the date
+// being changed, or today if it isn't set
+func (m uiModel) dateInputBase(dateType string) time.Time {
+	if m.editingItem != nil {
+		if dateType == "DEADLINE" && m.editingItem.Deadline != nil {
+			return *m.editingItem.Deadline
 		}
-		return time.Now().AddDate(0, 0, days), nil
-	}
-
-	// Try parsing as absolute date
-	formats := []string{
-		"2006-01-02",
-		"2006/01/02",
-		"01/02/2006",
-	}
-
-	for _, format := range formats {
-		if t, err := time.Parse(format, input); err == nil {
-			return t, nil
+		if dateType == "SCHEDULED" && m.editingItem.Scheduled != nil {
+			return *m.editingItem.Scheduled
 		}
 	}
-
-	return time.Time{}, fmt.Errorf("unable to parse date: %s (use YYYY-MM-DD or +N)", input)
+	return m.config.Now()
 }
 
 func (m uiModel) updateSetScheduled(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -582,18 +572,20 @@ func (m uiModel) updateSetDate(msg tea.Msg, dateType string) (tea.Model, tea.Cmd
 	case tea.KeyMsg:
 		switch msg.Type {
 		case tea.KeyEnter:
+			if m.calendarFocused {
+				// Confirm the day picked in the calendar
+				m.moveCalendar(dateType, 0, 0)
+			}
 			input := strings.TrimSpace(m.textinput.Value())
 			if m.editingItem != nil {
-				var prefixDate string
 				var clearedDateMsg string
 				var setDateMsg string
+				deadlineSet := false
 
 				if dateType == "DEADLINE" {
-					prefixDate = "DEADLINE:"
 					clearedDateMsg = "Deadline cleared!"
 					setDateMsg = "Deadline set!"
 				} else {
-					prefixDate = "SCHEDULED:"
 					clearedDateMsg = "Scheduled date cleared!"
 					setDateMsg = "Scheduled date set!"
 				}
@@ -602,56 +594,44 @@ func (m uiModel) updateSetDate(msg tea.Msg, dateType string) (tea.Model, tea.Cmd
 					// Empty input clears the date
 					if dateType == "DEADLINE" {
 						m.editingItem.Deadline = nil
+						m.editingItem.DeadlineEnd = nil
+						m.editingItem.DeadlineRepeater = ""
+						m.editingItem.DeadlineWarning = ""
+						m.editingItem.DeadlineHasTime = false
 					} else {
 						m.editingItem.Scheduled = nil
+						m.editingItem.ScheduledEnd = nil
+						m.editingItem.ScheduledRepeater = ""
+						m.editingItem.ScheduledDelay = ""
+						m.editingItem.ScheduledHasTime = false
 					}
-
-					// Remove property line from notes
-					var filteredNotes []string
-					for _, note := range m.editingItem.Notes {
-						trimmedNote := strings.TrimSpace(note)
-						if !strings.HasPrefix(trimmedNote, prefixDate) {
-							filteredNotes = append(filteredNotes, note)
-						}
-					}
-					m.editingItem.Notes = filteredNotes
 					m.setStatus(clearedDateMsg)
 				} else {
-					dateVal, err := parseDateInput(input)
+					parsed, err := parseDateInput(input, m.dateInputBase(dateType), m.config.Now())
 					if err != nil {
 						m.setStatus(fmt.Sprintf("Invalid date: %v", err))
 					} else {
+						dateVal := parsed.date
 						if dateType == "DEADLINE" {
 							m.editingItem.Deadline = &dateVal
+							m.editingItem.DeadlineEnd = parsed.end
+							m.editingItem.DeadlineHasTime = parsed.hasTime
+							deadlineSet = true
 						} else {
 							m.editingItem.Scheduled = &dateVal
-						}
-
-						// Also update or add property line in notes
-						updatedNotes := false
-						for i, note := range m.editingItem.Notes {
-							trimmedNote := strings.TrimSpace(note)
-							if strings.HasPrefix(trimmedNote, prefixDate) {
-								m.editingItem.Notes[i] = fmt.Sprintf("%s <%s>", prefixDate, parser.FormatOrgDate(dateVal))
-								updatedNotes = true
-								break
-							}
-						}
-						// If property wasn't in notes, it will be added by writeItem
-						if !updatedNotes {
-							// Remove old property lines just to be safe
-							var filteredNotes []string
-							for _, note := range m.editingItem.Notes {
-								trimmedNote := strings.TrimSpace(note)
-								if !strings.HasPrefix(trimmedNote, prefixDate) {
-									filteredNotes = append(filteredNotes, note)
-								}
-							}
-							m.editingItem.Notes = filteredNotes
+							m.editingItem.ScheduledEnd = parsed.end
+							m.editingItem.ScheduledHasTime = parsed.hasTime
 						}
 						m.setStatus(setDateMsg)
 					}
 				}
+
+				// Keep planning lines in notes in sync (repeaters are preserved);
+				// if the date wasn't in notes, it will be added by writeItem
+				parser.UpdatePlanning(m.editingItem)
+				if deadlineSet {
+					m.queueHook(hooks.DeadlineSet, m.editingItem, nil)
+				}
 			}
 			m.mode = modeList
 			m.textinput.Blur()
@@ -663,10 +643,24 @@ func (m uiModel) updateSetDate(msg tea.Msg, dateType string) (tea.Model, tea.Cmd
 			m.editingItem = nil
 			m.setStatus("Cancelled")
 			return m, nil
+		case tea.KeyTab:
+			return m, m.focusCalendar(!m.calendarFocused)
+		}
+		if m.calendarFocused {
+			if m.calendarKey(dateType, msg.String()) {
+				return m, nil
+			}
+			// Anything else is typed into the input
+			focusCmd := m.focusCalendar(false)
+			m.textinput, cmd = m.textinput.Update(msg)
+			m.followDateInput(dateType)
+			return m, tea.Batch(focusCmd, cmd)
 		}
 	}
 
 	m.textinput, cmd = m.textinput.Update(msg)
+
+	m.followDateInput(dateType)
 	return m, cmd
 }
 
@@ -762,16 +756,17 @@ func (m uiModel) updateSetEffort(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *uiModel) cycleStateForward(item *model.Item) {
+// cycleStateForward moves the item to the next state
+// Returns false if the state was not changed
+func (m *uiModel) cycleStateForward(item *model.Item) bool {
 	stateNames := m.config.GetStateNames()
 	if len(stateNames) == 0 {
-		return
+		return false
 	}
 
 	// Find current state index
 	currentIndex := -1
 	currentState := string(item.State)
-	lastStateIndex := len(stateNames) - 1
 
 	// Handle empty state
 	if currentState == "" {
@@ -785,8 +780,6 @@ func (m *uiModel) cycleStateForward(item *model.Item) {
 		}
 	}
 
-	// Store the old state to check if we're transitioning to/from DONE
-	oldState := currentState
 	var newState string
 
 	// Cycle forward
@@ -800,49 +793,20 @@ func (m *uiModel) cycleStateForward(item *model.Item) {
 		newState = stateNames[currentIndex+1]
 	}
 
-	// Update the item state
-	item.State = model.TodoState(newState)
-
-	// Manage CLOSED timestamp
-	wasInDoneState := (oldState == stateNames[lastStateIndex])
-	isInDoneState := (newState == stateNames[lastStateIndex])
-
-	if isInDoneState && !wasInDoneState {
-		// Moving TO done state - add CLOSED timestamp
-		now := time.Now()
-		item.Closed = &now
-		// Remove any existing CLOSED line from notes
-		var filteredNotes []string
-		for _, note := range item.Notes {
-			if !strings.HasPrefix(strings.TrimSpace(note), "CLOSED:") {
-				filteredNotes = append(filteredNotes, note)
-			}
-		}
-		item.Notes = filteredNotes
-	} else if wasInDoneState && !isInDoneState {
-		// Moving FROM done state - remove CLOSED timestamp
-		item.Closed = nil
-		// Remove any existing CLOSED line from notes
-		var filteredNotes []string
-		for _, note := range item.Notes {
-			if !strings.HasPrefix(strings.TrimSpace(note), "CLOSED:") {
-				filteredNotes = append(filteredNotes, note)
-			}
-		}
-		item.Notes = filteredNotes
-	}
+	return m.applyStateChange(item, newState)
 }
 
-func (m *uiModel) cycleStateBackward(item *model.Item) {
+// cycleStateBackward moves the item to the previous state
+// Returns false if the state was not changed
+func (m *uiModel) cycleStateBackward(item *model.Item) bool {
 	stateNames := m.config.GetStateNames()
 	if len(stateNames) == 0 {
-		return
+		return false
 	}
 
 	// Find current state index
 	currentIndex := -1
 	currentState := string(item.State)
-	lastStateIndex := len(stateNames) - 1
 
 	// Handle empty state
 	if currentState == "" {
@@ -856,8 +820,6 @@ func (m *uiModel) cycleStateBackward(item *model.Item) {
 		}
 	}
 
-	// Store the old state to check if we're transitioning to/from DONE
-	oldState := currentState
 	var newState string
 
 	// Cycle backward
@@ -869,16 +831,62 @@ func (m *uiModel) cycleStateBackward(item *model.Item) {
 		newState = stateNames[currentIndex-1]
 	}
 
+	return m.applyStateChange(item, newState)
+}
+
+// applyStateChange moves the item to newState, enforcing dependencies, logging the
+// change, managing the CLOSED timestamp and rescheduling repeating tasks
+// Returns false if the change was refused
+func (m *uiModel) applyStateChange(item *model.Item, newState string) bool {
+	// File items in multi-file mode aren't headings, so the change and its log entry
+	// would never be saved
+	isMultiFile := len(m.orgFile.Items) > 0 && m.orgFile.Items[0].SourceFile != ""
+	if isMultiFile && item.Level == 1 && item.SourceFile != "" {
+		m.setStatus("Cannot change the state of file-level items")
+		return false
+	}
+
+	// Refuse to complete an item while its dependencies are still open
+	if !m.checkBlockers(item, newState) {
+		return false
+	}
+
+	// Store the old state to check if we're transitioning to/from DONE
+	oldState := string(item.State)
+	wasInDoneState := m.config.IsDoneState(oldState)
+	isInDoneState := m.config.IsDoneState(newState)
+
 	// Update the item state
 	item.State = model.TodoState(newState)
+	m.setStatus("State changed")
+
+	// Hooks see the item once the change is complete
+	defer func() {
+		m.queueHook(hooks.StateChanged, item, map[string]string{"from": oldState, "to": newState})
+		// Completing a repeating task reopens it, which hooks see as a second change
+		if finalState := string(item.State); finalState != newState {
+			m.queueHook(hooks.StateChanged, item, map[string]string{"from": newState, "to": finalState})
+		}
+	}()
 
-	// Manage CLOSED timestamp
-	wasInDoneState := (oldState == stateNames[lastStateIndex])
-	isInDoneState := (newState == stateNames[lastStateIndex])
+	// Auto clock out when changing to the done state
+	if isInDoneState && item.IsClockedIn() {
+		item.ClockOut(m.config.Now())
+		m.queueHook(hooks.ClockOut, item, nil)
+	}
+
+	// Completing a repeating task reschedules it instead of closing it
+	if isInDoneState && !wasInDoneState && item.IsRepeating() {
+		m.repeatItem(item, oldState)
+		return true
+	}
+
+	m.recordStateChange(item, oldState, newState)
 
+	// Manage CLOSED timestamp
 	if isInDoneState && !wasInDoneState {
 		// Moving TO done state - add CLOSED timestamp
-		now := time.Now()
+		now := m.config.Now()
 		item.Closed = &now
 		// Remove any existing CLOSED line from notes
 		var filteredNotes []string
@@ -900,6 +908,107 @@ func (m *uiModel) cycleStateBackward(item *model.Item) {
 		}
 		item.Notes = filteredNotes
 	}
+	return true
+}
+
+// repeatItem handles completing a task with a repeater: the completion is logged,
+// the dates are shifted to their next occurrence and the task is reopened
+func (m *uiModel) repeatItem(item *model.Item, oldState string) {
+	now := m.config.Now()
+	doneState := item.State
+
+	// Repeated completions are always logged, since the LOGBOOK is their only record
+	if logChange, _ := m.config.GetStateLogging(string(doneState)); logChange {
+		m.recordStateChange(item, oldState, string(doneState))
+	} else {
+		parser.LogStateChange(item, model.StateChange{
+			From: model.TodoState(oldState),
+			To:   doneState,
+			Time: now,
+		})
+	}
+
+	item.AdvanceRepeats(now)
+	parser.UpdatePlanning(item)
+	item.SetProperty("LAST_REPEAT", "["+parser.FormatOrgDateTime(now)+"]")
+
+	// Reopen in the state given by :REPEAT_TO_STATE:, or the first state
+	reopenState := item.GetProperty("REPEAT_TO_STATE")
+	if reopenState == "" {
+		reopenState = m.config.GetStateNames()[0]
+	}
+	item.State = model.TodoState(reopenState)
+
+	if m.mode != modeStateNote {
+		if item.Scheduled != nil {
+			m.setStatus("Repeating task - next on " + parser.FormatOrgDate(*item.Scheduled))
+		} else if item.Deadline != nil {
+			m.setStatus("Repeating task - next due " + parser.FormatOrgDate(*item.Deadline))
+		}
+	}
+}
+
+// recordStateChange logs a state transition in the item's LOGBOOK if the new state
+// is configured for logging, switching to the note prompt if it asks for a note
+func (m *uiModel) recordStateChange(item *model.Item, oldState, newState string) {
+	logChange, logNote := m.config.GetStateLogging(newState)
+	if !logChange {
+		return
+	}
+
+	change := model.StateChange{
+		From: model.TodoState(oldState),
+		To:   model.TodoState(newState),
+		Time: m.config.Now(),
+	}
+
+	if logNote {
+		// The change is logged once the note has been entered (or skipped)
+		m.pendingChange = &change
+		m.editingItem = item
+		m.mode = modeStateNote
+		m.textinput.SetValue("")
+		m.textinput.Placeholder = "Reason for the change (optional)"
+		m.textinput.Focus()
+		return
+	}
+
+	parser.LogStateChange(item, change)
+}
+
+// checkBlockers returns false and sets a status message naming the open blockers
+// if the item would be moved to the done state while its dependencies are open
+func (m *uiModel) checkBlockers(item *model.Item, newState string) bool {
+	if !m.config.IsDoneState(newState) {
+		return true
+	}
+
+	blockers := m.getOpenBlockers(item)
+	if len(blockers) == 0 {
+		return true
+	}
+
+	m.setStatus("Cannot complete - blocked by: " + blockerNames(blockers))
+	return false
+}
+
+// getOpenBlockers returns the unfinished items blocking the given item
+func (m uiModel) getOpenBlockers(item *model.Item) []*model.Item {
+	return m.orgFile.OpenBlockers(item, m.isDoneState)
+}
+
+// isDoneState reports whether a state counts as completed
+func (m uiModel) isDoneState(state model.TodoState) bool {
+	return m.config.IsDoneState(string(state))
+}
+
+// blockerNames returns a comma-separated list of blocker titles
+func blockerNames(blockers []*model.Item) string {
+	names := make([]string, len(blockers))
+	for i, blocker := range blockers {
+		names[i] = blocker.Title
+	}
+	return strings.Join(names, ", ")
 }
 
 func (m *uiModel) deleteItem(item *model.Item) {
@@ -1279,6 +1388,7 @@ func (m *uiModel) updateRename(msg tea.Msg) (tea.Model, tea.Cmd) {
 				newTitle := strings.TrimSpace(m.textinput.Value())
 				if newTitle != "" {
 					m.editingItem.Title = newTitle
+					parser.ScanTimestamps(m.editingItem, m.config.Zone())
 					m.setStatus("Item renamed")
 				} else {
 					m.setStatus("Cannot rename to empty title")
@@ -1304,3 +1414,61 @@ func (m *uiModel) updateRename(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 	return m, nil
 }
+
+// updateStateNote handles the note prompt shown when entering a state that asks for one
+func (m uiModel) updateStateNote(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.textinput.Width = 50
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyEnter, tea.KeyEsc:
+			if m.editingItem != nil && m.pendingChange != nil {
+				// Escape skips the note but still records the change
+				if msg.Type == tea.KeyEnter {
+					m.pendingChange.Note = strings.TrimSpace(m.textinput.Value())
+				}
+				parser.LogStateChange(m.editingItem, *m.pendingChange)
+				m.setStatus(fmt.Sprintf("State changed to %s (logged)", m.pendingChange.To))
+			}
+			m.mode = modeList
+			m.textinput.Blur()
+			m.editingItem = nil
+			m.pendingChange = nil
+			return m, nil
+		}
+	}
+
+	m.textinput, cmd = m.textinput.Update(msg)
+	return m, cmd
+}
+
+// updateHistory handles the state history panel
+func (m uiModel) updateHistory(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case tea.KeyMsg:
+		switch {
+		case msg.Type == tea.KeyEsc, key.Matches(msg, m.keys.Quit), key.Matches(msg, m.keys.ShowHistory):
+			m.mode = modeList
+			m.editingItem = nil
+			m.historyScroll = 0
+		case key.Matches(msg, m.keys.Up):
+			if m.historyScroll > 0 {
+				m.historyScroll--
+			}
+		case key.Matches(msg, m.keys.Down):
+			// The view clamps the scroll position
+			m.historyScroll++
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rwejlgaard/org/internal/notify"
+)
+
+// reminderTickMsg asks for a check for reminders that are due
+type reminderTickMsg struct{}
+
+// reminderResultMsg reports the reminders that were sent in the background
+type reminderResultMsg struct {
+	sent []notify.Reminder
+	err  error
+}
+
+// checkReminders finds the reminders that are due and returns a command that sends them
+// in the background and checks again a minute later.
+//
+// Without a reminder hook, reminders are only shown in the status line, as writing to the
+// terminal here would get in the way of the program drawing the screen. Those are
+// remembered here rather than recorded as sent, so that `org notify` still sends them
+// when it is running too.
+func (m *uiModel) checkReminders() tea.Cmd {
+	next := tea.Tick(time.Minute, func(time.Time) tea.Msg { return reminderTickMsg{} })
+
+	now := m.config.Now()
+	var hooked, shown []notify.Reminder
+	for _, reminder := range notify.Due(m.orgFile, m.config, now) {
+		if reminder.Hooked() {
+			hooked = append(hooked, reminder)
+		} else if !m.shownReminders[reminder.Key] {
+			m.shownReminders[reminder.Key] = true
+			shown = append(shown, reminder)
+		}
+	}
+	if len(hooked) == 0 && len(shown) == 0 {
+		return next
+	}
+	return tea.Batch(next, func() tea.Msg {
+		// Deliver skips the reminders that were already sent, and as hooked reminders
+		// don't write to the terminal, nothing is written to w
+		sent, err := notify.Deliver(hooked, now, io.Discard)
+		return reminderResultMsg{sent: append(sent, shown...), err: err}
+	})
+}
+
+// showReminders puts the reminders that were sent in the status line
+func (m *uiModel) showReminders(msg reminderResultMsg) {
+	if len(msg.sent) == 0 {
+		if msg.err != nil {
+			m.setStatus(fmt.Sprintf("Reminders: %v", msg.err))
+		}
+		return
+	}
+	reminder := msg.sent[len(msg.sent)-1]
+	status := fmt.Sprintf("Reminder: %s - %s", reminder.Title, reminder.Message)
+	if len(msg.sent) > 1 {
+		status += fmt.Sprintf(" (+%d more)", len(msg.sent)-1)
+	}
+	if msg.err != nil {
+		status += fmt.Sprintf(" (reminders: %v)", msg.err)
+	}
+	m.setStatus(status)
+	// Leave reminders up for longer than other messages, as they weren't asked for
+	m.statusExpiry = time.Now().Add(time.Minute)
+}
@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rwejlgaard/org/internal/hooks"
+	"github.com/rwejlgaard/org/internal/model"
+)
+
+// pomodoroProperty counts the pomodoros completed on an item
+const pomodoroProperty = "POMODOROS"
+
+// pomodoroTimer is a running pomodoro cycle. The item is clocked in while working and
+// clocked out during the break.
+type pomodoroTimer struct {
+	item     *model.Item
+	breaking bool
+	ends     time.Time
+	id       int // Tells the ticks of this timer apart from those of a stopped one
+}
+
+// pomodoroTickMsg updates the countdown of the timer with the same id
+type pomodoroTickMsg struct {
+	id int
+}
+
+func (p *pomodoroTimer) tick() tea.Cmd {
+	id := p.id
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return pomodoroTickMsg{id: id} })
+}
+
+// startPomodoro starts a pomodoro timer on the item, clocking in for the first work period
+func (m *uiModel) startPomodoro(item *model.Item) tea.Cmd {
+	m.pomodoroRuns++
+	m.pomodoro = &pomodoroTimer{item: item, id: m.pomodoroRuns}
+	m.startPomodoroWork()
+	m.setStatus(fmt.Sprintf("Pomodoro started: %d minutes", m.config.Pomodoro.WorkMinutes))
+	return m.pomodoro.tick()
+}
+
+// startPomodoroWork clocks the item in for the next work period
+func (m *uiModel) startPomodoroWork() {
+	p := m.pomodoro
+	p.breaking = false
+	p.ends = time.Now().Add(time.Duration(m.config.Pomodoro.WorkMinutes) * time.Minute)
+	if p.item.ClockIn(m.config.Now()) {
+		m.queueHook(hooks.ClockIn, p.item, nil)
+	}
+	m.queueHook(hooks.Pomodoro, p.item, pomodoroHookExtra(p.item, "work"))
+}
+
+// startPomodoroBreak records the completed pomodoro and clocks the item out for the break
+func (m *uiModel) startPomodoroBreak() {
+	p := m.pomodoro
+	p.breaking = true
+	p.ends = time.Now().Add(time.Duration(m.config.Pomodoro.BreakMinutes) * time.Minute)
+	if p.item.ClockOut(m.config.Now()) {
+		m.queueHook(hooks.ClockOut, p.item, nil)
+	}
+	count, _ := strconv.Atoi(p.item.GetProperty(pomodoroProperty))
+	p.item.SetProperty(pomodoroProperty, strconv.Itoa(count+1))
+	m.queueHook(hooks.Pomodoro, p.item, pomodoroHookExtra(p.item, "break"))
+}
+
+// stopPomodoro stops the timer, clocking out if it was in a work period. The unfinished
+// pomodoro isn't counted.
+func (m *uiModel) stopPomodoro() {
+	p := m.pomodoro
+	m.pomodoro = nil
+	if !p.breaking && p.item.ClockOut(m.config.Now()) {
+		m.queueHook(hooks.ClockOut, p.item, nil)
+	}
+	m.queueHook(hooks.Pomodoro, p.item, pomodoroHookExtra(p.item, "stopped"))
+}
+
+// pomodoroHookExtra tells the pomodoro hook the phase the timer is entering and the
+// number of pomodoros completed on the item
+func pomodoroHookExtra(item *model.Item, phase string) map[string]string {
+	return map[string]string{
+		"phase":     phase,
+		"pomodoros": item.GetProperty(pomodoroProperty),
+	}
+}
+
+// tickPomodoro switches between work and break when the current period is over
+func (m *uiModel) tickPomodoro(msg pomodoroTickMsg) tea.Cmd {
+	p := m.pomodoro
+	if p == nil || p.id != msg.id {
+		return nil
+	}
+
+	// The timer goes with the item if it was deleted or clocked out by hand
+	if !m.containsItem(p.item) || (!p.breaking && !p.item.IsClockedIn()) {
+		m.pomodoro = nil
+		m.queueHook(hooks.Pomodoro, p.item, pomodoroHookExtra(p.item, "stopped"))
+		m.setStatus("Pomodoro stopped")
+		return nil
+	}
+
+	if time.Now().Before(p.ends) {
+		return p.tick()
+	}
+	if p.breaking {
+		m.startPomodoroWork()
+		m.setStatus(fmt.Sprintf("Break over, back to %s", p.item.Title))
+	} else {
+		m.startPomodoroBreak()
+		m.setStatus(fmt.Sprintf("Pomodoro done, take a %d minute break", m.config.Pomodoro.BreakMinutes))
+	}
+	// Leave the switch up for longer than other messages, as it wasn't asked for
+	m.statusExpiry = time.Now().Add(time.Minute)
+	return p.tick()
+}
+
+func (m *uiModel) containsItem(target *model.Item) bool {
+	for _, item := range m.orgFile.AllItems() {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+// pomodoroIndicator is the countdown shown next to the title
+func (m uiModel) pomodoroIndicator() string {
+	p := m.pomodoro
+	if p == nil {
+		return ""
+	}
+	left := time.Until(p.ends).Round(time.Second)
+	if left < 0 {
+		left = 0
+	}
+	label := "POMODORO"
+	style := m.styles.dueTodayStyle
+	if p.breaking {
+		label = "BREAK"
+		style = m.styles.doneStyle
+	}
+	minutes := int(left.Minutes())
+	seconds := int(left.Seconds()) % 60
+	return style.Render(fmt.Sprintf(" [%s %02d:%02d]", label, minutes, seconds))
+}
@@ -235,8 +235,12 @@ func (m *uiModel) startSettingsEdit() {
 			return
 		}
 		state := m.config.States.States[stateIndex]
-		m.textinput.SetValue(state.Name + "," + state.Color)
-		m.textinput.Placeholder = "name,color (e.g., TODO,202)"
+		value := state.Name + "," + state.Color
+		if logFlag := stateLogFlag(state.Log, state.LogNote); logFlag != "" {
+			value += "," + logFlag
+		}
+		m.textinput.SetValue(value)
+		m.textinput.Placeholder = "name,color[,!|@] (e.g., TODO,202 or BLOCK,196,@)"
 		m.textinput.Focus()
 
 	case settingsSectionKeybindings:
@@ -339,6 +343,13 @@ func (m *uiModel) saveSettingsEdit() {
 			state := &m.config.States.States[stateIndex]
 			state.Name = strings.TrimSpace(parts[0])
 			state.Color = strings.TrimSpace(parts[1])
+			// Optional logging flag: "!" logs a timestamp, "@" also prompts for a note
+			logFlag := ""
+			if len(parts) >= 3 {
+				logFlag = strings.TrimSpace(parts[2])
+			}
+			state.Log = logFlag == "!" || logFlag == "@"
+			state.LogNote = logFlag == "@"
 			m.setStatus(fmt.Sprintf("Updated state '%s' (saved)", state.Name))
 		} else {
 			m.setStatus("Invalid format. Use: name,color")
@@ -741,6 +752,11 @@ func (m *uiModel) viewSettingsStates() string {
 		stateStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(state.Color))
 		line += stateStyle.Render(state.Name)
 		line += fmt.Sprintf(" (color: %s)", state.Color)
+		if state.LogNote {
+			line += m.styles.statusStyle.Render(" [log with note]")
+		} else if state.Log {
+			line += m.styles.statusStyle.Render(" [log]")
+		}
 
 		content.WriteString(line + "\n")
 	}
@@ -1036,3 +1052,14 @@ func (m *uiModel) moveSettingsItemDown() {
 		return
 	}
 }
+
+// stateLogFlag returns the org-style logging flag for a state ("!" or "@")
+func stateLogFlag(logChange, logNote bool) string {
+	if logNote {
+		return "@"
+	}
+	if logChange {
+		return "!"
+	}
+	return ""
+}
@@ -15,6 +15,8 @@ type styleMap struct {
 	titleStyle     lipgloss.Style
 	scheduledStyle lipgloss.Style
 	overdueStyle   lipgloss.Style
+	dueTodayStyle  lipgloss.Style
+	warningStyle   lipgloss.Style
 	statusStyle    lipgloss.Style
 	noteStyle      lipgloss.Style
 	foldedStyle    lipgloss.Style
@@ -33,6 +35,8 @@ func newStyleMapFromConfig(cfg *config.Config) styleMap {
 		titleStyle:     lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(colors.Title)),
 		scheduledStyle: lipgloss.NewStyle().Foreground(lipgloss.Color(colors.Scheduled)),
 		overdueStyle:   lipgloss.NewStyle().Foreground(lipgloss.Color(colors.Overdue)),
+		dueTodayStyle:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(colors.DueToday)),
+		warningStyle:   lipgloss.NewStyle().Foreground(lipgloss.Color(colors.Warning)),
 		statusStyle:    lipgloss.NewStyle().Foreground(lipgloss.Color(colors.Status)).Italic(true),
 		noteStyle:      lipgloss.NewStyle().Foreground(lipgloss.Color(colors.Note)).Italic(true),
 		foldedStyle:    lipgloss.NewStyle().Foreground(lipgloss.Color(colors.Folded)),
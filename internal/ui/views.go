@@ -5,12 +5,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/alecthomas/chroma/v2/quick"
+	"github.com/alecthomas/chroma/v2/formatters"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/rwejlgaard/org/internal/model"
 	"github.com/rwejlgaard/org/internal/parser"
+	"github.com/rwejlgaard/org/internal/render"
 )
 
 // dynamicKeyMap is a helper type for rendering keybindings with dynamic layout
@@ -74,6 +75,8 @@ func (m uiModel) View() string {
 		return m.viewConfirmDelete()
 	case modeCapture:
 		return m.viewCapture()
+	case modeCaptureTemplate:
+		return m.viewCaptureTemplate()
 	case modeAddSubTask:
 		return m.viewAddSubTask()
 	case modeSetDeadline:
@@ -96,6 +99,12 @@ func (m uiModel) View() string {
 		return m.viewTagEdit()
 	case modeRename:
 		return m.viewRename()
+	case modeStateNote:
+		return m.viewStateNote()
+	case modeExport:
+		return m.viewExport()
+	case modeHistory:
+		return m.viewHistory()
 	}
 
 	// Build footer (status + help)
@@ -131,6 +140,7 @@ func (m uiModel) View() string {
 	} else {
 		content.WriteString(m.styles.titleStyle.Render(title))
 	}
+	content.WriteString(m.pomodoroIndicator())
 	content.WriteString("\n\n")
 
 	// Calculate available height for items (total - title - footer)
@@ -165,7 +175,7 @@ func (m uiModel) View() string {
 			}
 			indent := notePrefix.String()
 			noteIndent := indent + "  "
-			filteredNotes := filterLogbookDrawer(item.Notes)
+			filteredNotes := parser.StripMetadata(item.Notes)
 			wrappedNotes := wrapNoteLines(filteredNotes, m.width, noteIndent)
 			highlightedNotes := m.renderNotesWithHighlighting(wrappedNotes)
 			lineCount += len(highlightedNotes)
@@ -191,6 +201,7 @@ func (m uiModel) View() string {
 
 	// Render items starting from scroll offset
 	itemLines := 0
+	blockerIndex := m.orgFile.BlockerIndex()
 	for i, item := range items {
 		// Calculate which line this item starts at
 		itemStartLine := 0
@@ -215,7 +226,7 @@ func (m uiModel) View() string {
 			if linesToSkip < itemLineCount[i] {
 				// Render the visible parts
 				if linesToSkip == 0 {
-					line := m.renderItem(item, i == m.cursor)
+					line := m.renderItem(item, i == m.cursor, blockerIndex)
 					content.WriteString(line)
 					content.WriteString("\n")
 					itemLines++
@@ -239,7 +250,7 @@ func (m uiModel) View() string {
 					}
 					indent := notePrefix.String()
 					noteIndent := indent + "  "
-					filteredNotes := filterLogbookDrawer(item.Notes)
+					filteredNotes := parser.StripMetadata(item.Notes)
 					wrappedNotes := wrapNoteLines(filteredNotes, m.width, noteIndent)
 					highlightedNotes := m.renderNotesWithHighlighting(wrappedNotes)
 					for noteIdx := linesToSkip - 1; noteIdx < len(highlightedNotes) && itemLines < availableHeight; noteIdx++ {
@@ -254,7 +265,7 @@ func (m uiModel) View() string {
 		}
 
 		// Render the full item
-		line := m.renderItem(item, i == m.cursor)
+		line := m.renderItem(item, i == m.cursor, blockerIndex)
 		content.WriteString(line)
 		content.WriteString("\n")
 		itemLines++
@@ -276,7 +287,7 @@ func (m uiModel) View() string {
 			}
 			indent := notePrefix.String()
 			noteIndent := indent + "  "
-			filteredNotes := filterLogbookDrawer(item.Notes)
+			filteredNotes := parser.StripMetadata(item.Notes)
 			wrappedNotes := wrapNoteLines(filteredNotes, m.width, noteIndent)
 			highlightedNotes := m.renderNotesWithHighlighting(wrappedNotes)
 			for _, note := range highlightedNotes {
@@ -336,26 +347,6 @@ func (m uiModel) viewConfirmDelete() string {
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, dialog)
 }
 
-func (m uiModel) viewCapture() string {
-	dialogStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("99")).
-		Padding(1, 2).
-		Width(60)
-
-	var content strings.Builder
-	content.WriteString(m.styles.titleStyle.Render("Capture TODO"))
-	content.WriteString("\n\n")
-	content.WriteString(m.textinput.View())
-	content.WriteString("\n\n")
-	content.WriteString(m.styles.statusStyle.Render("Press Enter to save • ESC to cancel"))
-
-	dialog := dialogStyle.Render(content.String())
-
-	// Center the dialog horizontally and vertically
-	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, dialog)
-}
-
 func (m uiModel) viewAddSubTask() string {
 	dialogStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
@@ -381,14 +372,14 @@ func (m uiModel) viewAddSubTask() string {
 }
 
 func (m uiModel) viewSetDeadline() string {
-	return m.viewSetDate("Set Deadline", "Leave empty to clear deadline")
+	return m.viewSetDate("Set Deadline", "DEADLINE", "Leave the input empty to clear the deadline")
 }
 
 func (m uiModel) viewSetScheduled() string {
-	return m.viewSetDate("Set Scheduled Date", "Leave empty to clear scheduled date")
+	return m.viewSetDate("Set Scheduled Date", "SCHEDULED", "Leave the input empty to clear the scheduled date")
 }
 
-func (m uiModel) viewSetDate(title, helpMsg string) string {
+func (m uiModel) viewSetDate(title, dateType, helpMsg string) string {
 	dialogStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(lipgloss.Color("141")).
@@ -403,11 +394,31 @@ func (m uiModel) viewSetDate(title, helpMsg string) string {
 	}
 	content.WriteString("\n\n")
 	content.WriteString(m.textinput.View())
+	content.WriteString("\n")
+
+	// Preview the date the input resolves to
+	if input := strings.TrimSpace(m.textinput.Value()); input != "" {
+		if parsed, err := parseDateInput(input, m.dateInputBase(dateType), m.config.Now()); err == nil {
+			content.WriteString(m.styles.titleStyle.Render("→ " + parsed.String()))
+		} else {
+			content.WriteString(m.styles.statusStyle.Render("→ " + err.Error()))
+		}
+	}
 	content.WriteString("\n\n")
-	content.WriteString(m.styles.statusStyle.Render("Examples: 2025-12-31, +7 (7 days from now)"))
+	content.WriteString(m.renderCalendar())
+	content.WriteString("\n\n")
+	content.WriteString(m.styles.statusStyle.Render("Examples: 2025-12-31, 15, mon, next fri, +2w, ++3d"))
+	content.WriteString("\n")
+	content.WriteString(m.styles.statusStyle.Render("Add a time or range: tomorrow 14:00, fri 9:30-10:30"))
 	content.WriteString("\n")
 	content.WriteString(m.styles.statusStyle.Render(helpMsg))
 	content.WriteString("\n")
+	if m.calendarFocused {
+		content.WriteString(m.styles.statusStyle.Render("Arrows pick a day • Shift+arrows change month • Tab or type to edit"))
+	} else {
+		content.WriteString(m.styles.statusStyle.Render("Tab to pick a day in the calendar"))
+	}
+	content.WriteString("\n")
 	content.WriteString(m.styles.statusStyle.Render("Press Enter to save • ESC to cancel"))
 
 	dialog := dialogStyle.Render(content.String())
@@ -496,9 +507,9 @@ func (m uiModel) viewHelp() string {
 
 	// Group bindings by category
 	navigationBindings := []key.Binding{m.keys.Up, m.keys.Down, m.keys.Left, m.keys.Right}
-	itemBindings := []key.Binding{m.keys.ToggleFold, m.keys.EditNotes, m.keys.CycleState}
+	itemBindings := []key.Binding{m.keys.ToggleFold, m.keys.EditNotes, m.keys.CycleState, m.keys.ShowHistory, m.keys.Export}
 	taskBindings := []key.Binding{m.keys.Capture, m.keys.AddSubTask, m.keys.Delete}
-	timeBindings := []key.Binding{m.keys.ClockIn, m.keys.ClockOut, m.keys.SetDeadline, m.keys.SetScheduled, m.keys.SetEffort}
+	timeBindings := []key.Binding{m.keys.ClockIn, m.keys.ClockOut, m.keys.SetDeadline, m.keys.SetScheduled, m.keys.SetEffort, m.keys.Pomodoro}
 	organizationBindings := []key.Binding{m.keys.SetPriority, m.keys.TagItem, m.keys.ShiftUp, m.keys.ShiftDown, m.keys.ToggleReorder}
 	viewBindings := []key.Binding{m.keys.ToggleView, m.keys.Settings, m.keys.Save, m.keys.Help, m.keys.Quit}
 
@@ -619,55 +630,6 @@ func (m uiModel) viewEditMode() string {
 	return b.String()
 }
 
-// filterLogbookDrawer removes LOGBOOK and PROPERTIES drawer content and scheduling metadata from notes
-func filterLogbookDrawer(notes []string) []string {
-	var filtered []string
-	inLogbook := false
-	inProperties := false
-
-	for _, note := range notes {
-		trimmed := strings.TrimSpace(note)
-
-		// Check for start of LOGBOOK drawer
-		if trimmed == ":LOGBOOK:" {
-			inLogbook = true
-			continue
-		}
-
-		// Check for start of PROPERTIES drawer
-		if trimmed == ":PROPERTIES:" {
-			inProperties = true
-			continue
-		}
-
-		// Check for end of drawer
-		if trimmed == ":END:" {
-			if inLogbook {
-				inLogbook = false
-				continue
-			}
-			if inProperties {
-				inProperties = false
-				continue
-			}
-		}
-
-		// Skip lines inside LOGBOOK or PROPERTIES drawer
-		if inLogbook || inProperties {
-			continue
-		}
-
-		// Skip SCHEDULED, DEADLINE, and CLOSED lines
-		if strings.HasPrefix(trimmed, "SCHEDULED:") || strings.HasPrefix(trimmed, "DEADLINE:") || strings.HasPrefix(trimmed, "CLOSED:") {
-			continue
-		}
-
-		filtered = append(filtered, note)
-	}
-
-	return filtered
-}
-
 // wrapNoteLines wraps note lines to fit within the specified width
 func wrapNoteLines(notes []string, width int, indent string) []string {
 	var wrapped []string
@@ -747,7 +709,7 @@ func (m uiModel) renderNotesWithHighlighting(notes []string) []string {
 					if codeLanguage == "latex" {
 						// Apply LaTeX-to-Unicode conversion
 						for _, line := range codeLines {
-							processedLines = append(processedLines, renderLatexMath(line))
+							processedLines = append(processedLines, render.RenderLatexMath(line))
 						}
 					} else {
 						// Apply syntax highlighting
@@ -773,7 +735,7 @@ func (m uiModel) renderNotesWithHighlighting(notes []string) []string {
 				if codeLanguage == "latex" {
 					// Apply LaTeX-to-Unicode conversion
 					for _, line := range codeLines {
-						processedLines = append(processedLines, renderLatexMath(line))
+						processedLines = append(processedLines, render.RenderLatexMath(line))
 					}
 				} else {
 					// Apply syntax highlighting
@@ -809,7 +771,7 @@ func (m uiModel) renderNotesWithHighlighting(notes []string) []string {
 		if codeLanguage == "latex" {
 			// Apply LaTeX-to-Unicode conversion
 			for _, line := range codeLines {
-				processedLines = append(processedLines, renderLatexMath(line))
+				processedLines = append(processedLines, render.RenderLatexMath(line))
 			}
 		} else {
 			// Apply syntax highlighting
@@ -829,7 +791,7 @@ func highlightCode(code, language string) string {
 	}
 
 	var buf strings.Builder
-	err := quick.Highlight(&buf, code, language, "terminal256", "monokai")
+	err := render.Highlight(&buf, code, language, formatters.TTY256)
 	if err != nil {
 		// If highlighting fails, return the original code
 		return code
@@ -927,7 +889,9 @@ func wrapText(text string, width int, indent string) []string {
 	return result
 }
 
-func (m uiModel) renderItem(item *model.Item, isCursor bool) string {
+// renderItem renders an item's heading line. blockerIndex is built once for all the items
+// being rendered.
+func (m uiModel) renderItem(item *model.Item, isCursor bool, blockerIndex *model.BlockerIndex) string {
 	var b strings.Builder
 
 	// Indentation with subtle visual nesting guides
@@ -997,6 +961,13 @@ func (m uiModel) renderItem(item *model.Item, isCursor bool) string {
 		}
 	}
 
+	// Dependencies that prevent completing this item
+	if !m.config.IsDoneState(string(item.State)) {
+		if blockers := blockerIndex.OpenBlockers(item, m.isDoneState); len(blockers) > 0 {
+			b.WriteString(m.styles.blockStyle.Render(fmt.Sprintf(" [BLOCKED BY: %s]", blockerNames(blockers))))
+		}
+	}
+
 	// Effort
 	if item.Effort != "" {
 		effortStr := fmt.Sprintf(" (Effort: %s)", item.Effort)
@@ -1034,21 +1005,38 @@ func (m uiModel) renderItem(item *model.Item, isCursor bool) string {
 	}
 
 	// Scheduling info
-	now := time.Now()
+	now := m.config.Now()
 	if item.Scheduled != nil {
-		schedStr := fmt.Sprintf(" (Scheduled: %s)", parser.FormatOrgDate(*item.Scheduled))
-		if item.Scheduled.Before(now) {
+		schedStr := fmt.Sprintf(" (Scheduled: %s)", parser.FormatPlanningTimestamp(item.ScheduledTimestamp()))
+		if model.DaysUntil(now, planningEnd(item.Scheduled, item.ScheduledEnd)) < 0 {
 			b.WriteString(m.styles.overdueStyle.Render(schedStr))
 		} else {
 			b.WriteString(m.styles.scheduledStyle.Render(schedStr))
 		}
 	}
 	if item.Deadline != nil {
-		deadlineStr := fmt.Sprintf(" (Deadline: %s)", parser.FormatOrgDate(*item.Deadline))
-		if item.Deadline.Before(now) {
-			b.WriteString(m.styles.overdueStyle.Render(deadlineStr))
-		} else {
-			b.WriteString(m.styles.scheduledStyle.Render(deadlineStr))
+		deadlineStr := "Deadline: " + parser.FormatPlanningTimestamp(item.DeadlineTimestamp())
+		label, style := m.deadlineStatus(item, now)
+		if label != "" {
+			deadlineStr += ", " + label
+		}
+		b.WriteString(style.Render(" (" + deadlineStr + ")"))
+	}
+
+	// Next appointment (agenda only, as the list shows the notes it is in)
+	if m.mode == modeAgenda {
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		if appointment, ok := nextAppointment(item, startOfDay); ok {
+			appointmentStr := fmt.Sprintf(" (At: %s)", parser.FormatPlanningTimestamp(appointment))
+			b.WriteString(m.styles.scheduledStyle.Render(appointmentStr))
+		}
+	}
+
+	// Habit consistency graph (agenda only)
+	if m.mode == modeAgenda && item.IsHabit() {
+		if graph := m.renderHabitGraph(item); graph != "" {
+			b.WriteString("  ")
+			b.WriteString(graph)
 		}
 	}
 
@@ -1059,6 +1047,33 @@ func (m uiModel) renderItem(item *model.Item, isCursor bool) string {
 	return line
 }
 
+// deadlineStatus describes how close an item's deadline is, with a label like "due in 2d"
+// or "3d overdue" and the style to show it in. Deadlines before their warning period and
+// those of done items have no label.
+func (m uiModel) deadlineStatus(item *model.Item, now time.Time) (string, lipgloss.Style) {
+	if m.config.IsDoneState(string(item.State)) {
+		return "", m.styles.scheduledStyle
+	}
+	days := model.DaysUntil(now, *item.Deadline)
+	switch overdue := -model.DaysUntil(now, planningEnd(item.Deadline, item.DeadlineEnd)); {
+	case overdue > 0:
+		return fmt.Sprintf("%dd overdue", overdue), m.styles.overdueStyle
+	case days <= 0:
+		return "due today", m.styles.dueTodayStyle
+	case model.DaysUntil(now, item.DeadlineWarningStart(m.config.GetDeadlineWarningDays())) <= 0:
+		return fmt.Sprintf("due in %dd", days), m.styles.warningStyle
+	}
+	return "", m.styles.scheduledStyle
+}
+
+// planningEnd returns the last day of a planning date: the end of its range, or the date itself
+func planningEnd(date, end *time.Time) time.Time {
+	if end != nil {
+		return *end
+	}
+	return *date
+}
+
 // viewTagEdit renders the tag editing view
 func (m uiModel) viewTagEdit() string {
 	var content strings.Builder
@@ -1095,3 +1110,109 @@ func (m uiModel) viewRename() string {
 
 	return content.String()
 }
+
+// viewStateNote renders the note prompt for a logged state change
+func (m uiModel) viewStateNote() string {
+	dialogStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("99")).
+		Padding(1, 2).
+		Width(60)
+
+	var content strings.Builder
+	content.WriteString(m.styles.titleStyle.Render("State Change Note"))
+	content.WriteString("\n")
+	if m.editingItem != nil {
+		content.WriteString(m.styles.statusStyle.Render(fmt.Sprintf("For: %s", m.editingItem.Title)))
+		content.WriteString("\n")
+	}
+	if m.pendingChange != nil {
+		from := string(m.pendingChange.From)
+		if from == "" {
+			from = "(none)"
+		}
+		content.WriteString(m.styles.statusStyle.Render(fmt.Sprintf("%s → %s", from, m.pendingChange.To)))
+	}
+	content.WriteString("\n\n")
+	content.WriteString(m.textinput.View())
+	content.WriteString("\n\n")
+	content.WriteString(m.styles.statusStyle.Render("Press Enter to save • ESC to skip the note"))
+
+	dialog := dialogStyle.Render(content.String())
+
+	// Center the dialog horizontally and vertically
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, dialog)
+}
+
+// viewHistory renders the state change history of the selected item
+func (m uiModel) viewHistory() string {
+	var lines []string
+
+	lines = append(lines, m.styles.titleStyle.Render("State History"))
+	if m.editingItem != nil {
+		lines = append(lines, m.styles.statusStyle.Render(fmt.Sprintf("For: %s", m.editingItem.Title)))
+	}
+	lines = append(lines, "")
+
+	var changes []model.StateChange
+	if m.editingItem != nil {
+		changes = parser.GetStateChanges(m.editingItem, m.config.Zone())
+	}
+
+	if len(changes) == 0 {
+		lines = append(lines, m.styles.statusStyle.Render("No state changes logged for this item."))
+	}
+
+	timeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(m.config.Colors.Scheduled))
+	for _, change := range changes {
+		from := m.styles.statusStyle.Render("(none)")
+		if change.From != model.StateNone {
+			from = lipgloss.NewStyle().Foreground(lipgloss.Color(m.config.GetStateColor(string(change.From)))).Render(string(change.From))
+		}
+		to := m.styles.statusStyle.Render("(none)")
+		if change.To != model.StateNone {
+			to = lipgloss.NewStyle().Foreground(lipgloss.Color(m.config.GetStateColor(string(change.To)))).Render(string(change.To))
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s → %s", timeStyle.Render(change.Time.Format("2006-01-02 Mon 15:04")), from, to))
+		if change.Note != "" {
+			for _, noteLine := range strings.Split(change.Note, "\n") {
+				lines = append(lines, "    "+m.styles.noteStyle.Render(noteLine))
+			}
+		}
+	}
+
+	// Calculate visible area
+	footerLines := 2
+	availableHeight := m.height - footerLines
+	if availableHeight < 5 {
+		availableHeight = 5
+	}
+
+	startLine := m.historyScroll
+	if startLine > len(lines)-availableHeight {
+		startLine = len(lines) - availableHeight
+	}
+	if startLine < 0 {
+		startLine = 0
+	}
+	endLine := startLine + availableHeight
+	if endLine > len(lines) {
+		endLine = len(lines)
+	}
+
+	var content strings.Builder
+	for i := startLine; i < endLine; i++ {
+		content.WriteString(lines[i])
+		content.WriteString("\n")
+	}
+
+	var result strings.Builder
+	result.WriteString(content.String())
+	paddingNeeded := availableHeight - lipgloss.Height(content.String())
+	if paddingNeeded > 0 {
+		result.WriteString(strings.Repeat("\n", paddingNeeded))
+	}
+	result.WriteString(m.styles.statusStyle.Render("↑/↓ scroll • ESC to close"))
+
+	return result.String()
+}